@@ -5,23 +5,49 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
+	"sort"
 
 	"github.com/ONSdigital/aws-appsync-generator/pkg/graphql"
 	"github.com/pkg/errors"
 	flag "github.com/spf13/pflag"
 )
 
+// Exit status used by --diff when the output path is out of date
+const exitDifferences = 2
+
 var (
 	manifest = ""
+	dryRun   = false
+	diff     = false
 )
 
 func init() {
 	flag.StringVarP(&manifest, "manifest", "m", "manifest.yml", "manifest file to parse")
 	flag.StringVarP(&graphql.GeneratedFilesPath, "output", "o", graphql.GeneratedFilesPath, "path to output generated files to (CAUTION: will be emptied before write!)")
+	flag.BoolVar(&dryRun, "dry-run", false, "generate all files in memory and list them without writing anything")
+	flag.BoolVar(&diff, "diff", false, "show a unified diff of the generated files against the output path without writing anything")
+	flag.StringVarP(&graphql.Target, "target", "t", graphql.Target, "infrastructure to generate: terraform or cloudformation")
+	flag.StringVar(&graphql.TemplatesPath, "templates", "", "directory of resolver templates overriding the built-in templates (overrides the manifest 'templates' setting)")
+	flag.BoolVar(&graphql.Reproducible, "reproducible", graphql.Reproducible, "stamp generated files with a hash of the manifest instead of the generation time")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [validate] [flags]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 }
 
 func main() {
+	command := flag.Arg(0)
+	if command != "" && command != "validate" {
+		flag.Usage()
+		log.Fatalf("unknown command '%s'", command)
+	}
+
+	if graphql.Target != graphql.TargetTerraform && graphql.Target != graphql.TargetCloudFormation {
+		log.Fatalf("unsupported target '%s', must be one of %s or %s", graphql.Target, graphql.TargetTerraform, graphql.TargetCloudFormation)
+	}
+
 	body, err := ioutil.ReadFile(manifest)
 	if err != nil {
 		log.Fatal(errors.Wrapf(err, "failed to read manifest '%s'", manifest))
@@ -32,14 +58,85 @@ func main() {
 		log.Fatal(errors.Wrap(err, "failed to parse definition"))
 	}
 
-	if err := s.WriteAll(); err != nil {
-		fmt.Println(err)
-		for _, e := range s.Errors {
-			fmt.Printf("(error) %v\n", e.Error())
+	// A templates directory set in the manifest is relative to the manifest
+	if graphql.TemplatesPath == "" && s.Templates != "" {
+		graphql.TemplatesPath = s.Templates
+		if !filepath.IsAbs(s.Templates) {
+			graphql.TemplatesPath = filepath.Join(filepath.Dir(manifest), s.Templates)
+		}
+	}
+	if graphql.TemplatesPath != "" {
+		if info, err := os.Stat(graphql.TemplatesPath); err != nil || !info.IsDir() {
+			log.Fatalf("templates path '%s' is not a directory", graphql.TemplatesPath)
 		}
-		fmt.Println("DONE (with errors)")
+	}
+
+	// Always validate before generating so that a bad manifest never
+	// results in the output path being emptied
+	if errs := s.Validate(); len(errs) > 0 {
+		for _, e := range errs {
+			fmt.Printf("(error) %s:%v\n", manifest, e)
+		}
+		fmt.Println("INVALID")
 		os.Exit(1)
 	}
 
+	if command == "validate" {
+		fmt.Println("VALID")
+		return
+	}
+
+	if dryRun || diff {
+		generateOnly(s)
+		return
+	}
+
+	if err := s.WriteAll(); err != nil {
+		exitWithErrors(s, err)
+	}
+
+	fmt.Println("DONE")
+}
+
+// generateOnly renders the files in memory and either lists them or shows
+// how they differ from what is currently in the output path
+func generateOnly(s *graphql.Schema) {
+	files, err := s.Generate()
+	if err != nil {
+		exitWithErrors(s, err)
+	}
+
+	if dryRun {
+		names := []string{}
+		for name := range files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("would write: %s (%d bytes)\n", filepath.Join(graphql.GeneratedFilesPath, name), len(files[name]))
+		}
+	}
+
+	if diff {
+		d, err := graphql.Diff(files)
+		if err != nil {
+			log.Fatal(errors.Wrap(err, "failed to diff generated files"))
+		}
+		if d != "" {
+			fmt.Print(d)
+			fmt.Println("DONE (output is out of date)")
+			os.Exit(exitDifferences)
+		}
+	}
+
 	fmt.Println("DONE")
 }
+
+func exitWithErrors(s *graphql.Schema, err error) {
+	fmt.Println(err)
+	for _, e := range s.Errors {
+		fmt.Printf("(error) %v\n", e.Error())
+	}
+	fmt.Println("DONE (with errors)")
+	os.Exit(1)
+}
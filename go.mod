@@ -1,10 +1,12 @@
 module github.com/ONSdigital/aws-appsync-generator
 
-go 1.13
+go 1.16
 
 require (
 	github.com/pkg/errors v0.8.1
+	github.com/pmezard/go-difflib v1.0.0
 	github.com/spf13/pflag v1.0.5
 	github.com/stretchr/testify v1.4.0
 	gopkg.in/yaml.v2 v2.2.7
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,93 @@
+package graphql
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+const defaultAPIName = "record"
+
+var (
+	reAPIName       = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	reAPILogLevels  = regexp.MustCompile(`^(ALL|ERROR|NONE)$`)
+	defaultLogLevel = "ERROR"
+)
+
+type (
+	// API is the configuration of the appsync api itself
+	API struct {
+		// Name is used to name the api and its role, and to prefix the names
+		// of other generated resources. Defaults to "record"
+		Name string `yaml:"name"`
+
+		// If true, the api, its service role and any logging configuration
+		// are generated. Otherwise they must be declared elsewhere using the
+		// names the generated resources expect
+		Create bool `yaml:"create"`
+
+		Logging *APILogging `yaml:"logging,omitempty"`
+
+		// Authorization providers of the api. Defaults to AWS_IAM
+		Auth *APIAuth `yaml:"auth,omitempty"`
+	}
+
+	// APILogging is the cloudwatch logging configuration of the api
+	APILogging struct {
+		// Field log level: ALL, ERROR or NONE. Defaults to ERROR
+		Level                 string `yaml:"level"`
+		ExcludeVerboseContent bool   `yaml:"exclude_verbose_content"`
+	}
+
+	unmarshalAPI API
+)
+
+// UnmarshalYAML satisfies the custom unmarshaler interface for go-yaml. It is
+// called automatically by the YAML unmarshal to set and check defaults.
+func (a *API) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var u unmarshalAPI
+	if err := unmarshal(&u); err != nil {
+		return err
+	}
+
+	*a = API(u)
+
+	if a.Name == "" {
+		a.Name = defaultAPIName
+	}
+	if !reAPIName.MatchString(a.Name) {
+		return fmt.Errorf("api name '%s' must contain only letters, digits and underscores", a.Name)
+	}
+
+	if a.Logging != nil {
+		if a.Logging.Level == "" {
+			a.Logging.Level = defaultLogLevel
+		}
+		if !reAPILogLevels.MatchString(a.Logging.Level) {
+			return fmt.Errorf("api logging level '%s' must be one of ALL, ERROR or NONE", a.Logging.Level)
+		}
+	}
+	return nil
+}
+
+// GenerateBytes renders the api ready to be written to the output stream
+func (a *API) GenerateBytes() ([]byte, error) {
+	generated := bytes.Buffer{}
+
+	t, err := template.New(a.Name).Funcs(funcMap).Parse(apiTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := t.Execute(&generated, a); err != nil {
+		return nil, err
+	}
+	return generated.Bytes(), nil
+}
+
+// OutputName returns the file name to be written for the api
+func (a *API) OutputName() string {
+	return strings.ToLower(fmt.Sprintf("_api_%s.tf", a.Name))
+}
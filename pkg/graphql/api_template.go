@@ -0,0 +1,134 @@
+package graphql
+
+var apiTemplate = `
+resource "aws_appsync_graphql_api" "{{.Name}}" {
+	name				= "${terraform.workspace}-{{.Name}}"
+	authentication_type	= "{{.AuthenticationType}}"
+	schema				= file("${path.module}/schema.public.graphql")
+	{{- with .Auth }}{{ with .Default }}
+	{{- if eq .Type "AMAZON_COGNITO_USER_POOLS" }}
+
+	user_pool_config {
+		user_pool_id		= "{{.UserPoolID}}"
+		{{- if .AWSRegion }}
+		aws_region			= "{{.AWSRegion}}"
+		{{- end }}
+		{{- if .AppIDClientRegex }}
+		app_id_client_regex	= "{{.AppIDClientRegex}}"
+		{{- end }}
+		default_action		= "{{.DefaultAction}}"
+	}
+	{{- end }}
+	{{- if eq .Type "OPENID_CONNECT" }}
+
+	openid_connect_config {
+		issuer		= "{{.Issuer}}"
+		{{- if .ClientID }}
+		client_id	= "{{.ClientID}}"
+		{{- end }}
+		{{- if .AuthTTL }}
+		auth_ttl	= {{.AuthTTL}}
+		{{- end }}
+		{{- if .IatTTL }}
+		iat_ttl		= {{.IatTTL}}
+		{{- end }}
+	}
+	{{- end }}
+	{{- end }}{{ end }}
+	{{- range .AdditionalAuth }}
+
+	additional_authentication_provider {
+		authentication_type	= "{{.Type}}"
+		{{- if eq .Type "AMAZON_COGNITO_USER_POOLS" }}
+
+		user_pool_config {
+			user_pool_id		= "{{.UserPoolID}}"
+			{{- if .AWSRegion }}
+			aws_region			= "{{.AWSRegion}}"
+			{{- end }}
+			{{- if .AppIDClientRegex }}
+			app_id_client_regex	= "{{.AppIDClientRegex}}"
+			{{- end }}
+		}
+		{{- end }}
+		{{- if eq .Type "OPENID_CONNECT" }}
+
+		openid_connect_config {
+			issuer		= "{{.Issuer}}"
+			{{- if .ClientID }}
+			client_id	= "{{.ClientID}}"
+			{{- end }}
+			{{- if .AuthTTL }}
+			auth_ttl	= {{.AuthTTL}}
+			{{- end }}
+			{{- if .IatTTL }}
+			iat_ttl		= {{.IatTTL}}
+			{{- end }}
+		}
+		{{- end }}
+	}
+	{{- end }}
+	{{- if .Logging }}
+
+	log_config {
+		cloudwatch_logs_role_arn	= aws_iam_role.{{.Name}}_logging.arn
+		field_log_level				= "{{.Logging.Level}}"
+		exclude_verbose_content		= {{.Logging.ExcludeVerboseContent}}
+	}
+	{{- end }}
+
+	tags = {
+		Environment = terraform.workspace
+		Name        = "{{.Name}}"
+	}
+}
+
+resource "aws_iam_role" "{{.Name}}" {
+	name				= "${terraform.workspace}-{{.Name}}-appsync"
+	assume_role_policy	= <<EOF
+{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+    "Action": "sts:AssumeRole",
+    "Effect": "Allow",
+    "Principal": {
+      "Service": "appsync.amazonaws.com"
+    }
+    }
+  ]
+}
+EOF
+}
+{{- if .HasAPIKey }}
+
+resource "aws_appsync_api_key" "{{.Name}}" {
+	api_id		= aws_appsync_graphql_api.{{.Name}}.id
+}
+{{- end }}
+{{- if .Logging }}
+
+resource "aws_iam_role" "{{.Name}}_logging" {
+	name				= "${terraform.workspace}-{{.Name}}-appsync-logging"
+	assume_role_policy	= <<EOF
+{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+    "Action": "sts:AssumeRole",
+    "Effect": "Allow",
+    "Principal": {
+      "Service": "appsync.amazonaws.com"
+    }
+    }
+  ]
+}
+EOF
+}
+
+resource "aws_iam_role_policy_attachment" "{{.Name}}_logging" {
+	role		= aws_iam_role.{{.Name}}_logging.name
+	policy_arn	= "arn:aws:iam::aws:policy/service-role/AWSAppSyncPushToCloudWatchLogs"
+}
+{{- end }}
+`
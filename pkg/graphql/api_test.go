@@ -0,0 +1,79 @@
+package graphql_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ONSdigital/aws-appsync-generator/pkg/graphql"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestUnmarshalAPI(t *testing.T) {
+	for _, c := range []struct {
+		scenario string
+		yaml     []byte
+		expected *graphql.API
+		err      error
+	}{
+		{
+			"Default name",
+			[]byte("create: true"),
+			&graphql.API{Name: "record", Create: true},
+			nil,
+		},
+		{
+			"Named with default logging level",
+			[]byte("name: zoo\nlogging:\n  exclude_verbose_content: true"),
+			&graphql.API{
+				Name: "zoo",
+				Logging: &graphql.APILogging{
+					Level:                 "ERROR",
+					ExcludeVerboseContent: true,
+				},
+			},
+			nil,
+		},
+		{
+			"Bad name",
+			[]byte("name: my-api"),
+			nil,
+			errors.New("api name 'my-api' must contain only letters, digits and underscores"),
+		},
+		{
+			"Bad logging level",
+			[]byte("logging:\n  level: DEBUG"),
+			nil,
+			errors.New("api logging level 'DEBUG' must be one of ALL, ERROR or NONE"),
+		},
+	} {
+		var a graphql.API
+		err := yaml.Unmarshal(c.yaml, &a)
+		switch c.err {
+		case nil:
+			assert.NoError(t, err)
+			assert.Equal(t, c.expected, &a, c.scenario)
+		default:
+			assert.EqualError(t, err, c.err.Error())
+		}
+	}
+}
+
+func TestGenerateAPI(t *testing.T) {
+	files, err := mustCompileSchema(t, overrideManifest).Generate()
+	if err != nil {
+		t.Fatalf("error generating schema '%v', expected nil", err)
+	}
+	assert.NotContains(t, files, "_api_record.tf")
+	assert.Contains(t, string(files["_query_getperson.tf"]), "api_id            = aws_appsync_graphql_api.record.id")
+
+	manifest := append([]byte("api:\n  name: people\n  create: true\n"), overrideManifest...)
+	files, err = mustCompileSchema(t, manifest).Generate()
+	if err != nil {
+		t.Fatalf("error generating schema '%v', expected nil", err)
+	}
+	assert.Contains(t, string(files["_api_people.tf"]), `resource "aws_appsync_graphql_api" "people"`)
+	assert.Contains(t, string(files["_api_people.tf"]), `file("${path.module}/schema.public.graphql")`)
+	assert.Contains(t, string(files["_query_getperson.tf"]), "api_id            = aws_appsync_graphql_api.people.id")
+	assert.Contains(t, string(files["_datasource_dynamo_people.tf"]), "role 		= aws_iam_role.people.id")
+}
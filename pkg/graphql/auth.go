@@ -0,0 +1,195 @@
+package graphql
+
+import "fmt"
+
+// Supported authorization types
+const (
+	AuthAPIKey  = "API_KEY"
+	AuthIAM     = "AWS_IAM"
+	AuthCognito = "AMAZON_COGNITO_USER_POOLS"
+	AuthOIDC    = "OPENID_CONNECT"
+)
+
+// authDirectives maps each authorization type to the schema directive that
+// grants it access to a type or field
+var authDirectives = map[string]string{
+	AuthAPIKey:  "@aws_api_key",
+	AuthIAM:     "@aws_iam",
+	AuthCognito: "@aws_cognito_user_pools",
+	AuthOIDC:    "@aws_oidc",
+}
+
+type (
+	// APIAuth is the authorization configuration of the api. Where it isn't
+	// declared the api is authorized with AWS_IAM alone.
+	APIAuth struct {
+		Default    *AuthProvider   `yaml:"default"`
+		Additional []*AuthProvider `yaml:"additional"`
+	}
+
+	// AuthProvider is an authorization provider of the api
+	AuthProvider struct {
+		// API_KEY, AWS_IAM, AMAZON_COGNITO_USER_POOLS or OPENID_CONNECT
+		Type string `yaml:"type"`
+
+		// Cognito user pool configuration
+		UserPoolID       string `yaml:"user_pool_id"`
+		AWSRegion        string `yaml:"aws_region"`
+		AppIDClientRegex string `yaml:"app_id_client_regex"`
+
+		// Action taken when a cognito user doesn't match any of the groups
+		// given by a directive: ALLOW or DENY. Only applies to the default
+		// provider, where it defaults to ALLOW
+		DefaultAction string `yaml:"default_action"`
+
+		// OpenID connect configuration
+		Issuer   string `yaml:"issuer"`
+		ClientID string `yaml:"client_id"`
+		AuthTTL  int    `yaml:"auth_ttl"`
+		IatTTL   int    `yaml:"iat_ttl"`
+	}
+
+	// AuthList is a list of the authorization types allowed to access an
+	// object, field or operation
+	AuthList []string
+
+	unmarshalAPIAuth      APIAuth
+	unmarshalAuthProvider AuthProvider
+)
+
+// UnmarshalYAML satisfies the custom unmarshaler interface for go-yaml. It is
+// called automatically by the YAML unmarshal to set and check defaults.
+func (a *APIAuth) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var u unmarshalAPIAuth
+	if err := unmarshal(&u); err != nil {
+		return err
+	}
+
+	*a = APIAuth(u)
+
+	if a.Default == nil {
+		return fmt.Errorf("api auth does not declare a default provider")
+	}
+	if a.Default.Type == AuthCognito && a.Default.DefaultAction == "" {
+		a.Default.DefaultAction = "ALLOW"
+	}
+
+	seen := map[string]bool{a.Default.Type: true}
+	for _, p := range a.Additional {
+		if p.DefaultAction != "" {
+			return fmt.Errorf("api auth provider '%s' is not the default provider so cannot declare a default_action", p.Type)
+		}
+		// Only cognito and oidc can be configured more than once, against
+		// different user pools and issuers
+		if seen[p.Type] && (p.Type == AuthAPIKey || p.Type == AuthIAM) {
+			return fmt.Errorf("api auth provider '%s' is declared more than once", p.Type)
+		}
+		seen[p.Type] = true
+	}
+	return nil
+}
+
+// UnmarshalYAML satisfies the custom unmarshaler interface for go-yaml. It is
+// called automatically by the YAML unmarshal to set and check defaults.
+func (p *AuthProvider) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var u unmarshalAuthProvider
+	if err := unmarshal(&u); err != nil {
+		return err
+	}
+
+	*p = AuthProvider(u)
+
+	if _, ok := authDirectives[p.Type]; !ok {
+		return fmt.Errorf("api auth provider type '%s' must be one of API_KEY, AWS_IAM, AMAZON_COGNITO_USER_POOLS or OPENID_CONNECT", p.Type)
+	}
+
+	switch p.Type {
+	case AuthCognito:
+		if p.UserPoolID == "" {
+			return fmt.Errorf("api auth provider '%s' does not declare a user_pool_id", p.Type)
+		}
+		if p.DefaultAction != "" && p.DefaultAction != "ALLOW" && p.DefaultAction != "DENY" {
+			return fmt.Errorf("api auth default_action '%s' must be one of ALLOW or DENY", p.DefaultAction)
+		}
+	case AuthOIDC:
+		if p.Issuer == "" {
+			return fmt.Errorf("api auth provider '%s' does not declare an issuer", p.Type)
+		}
+	}
+
+	if p.DefaultAction != "" && p.Type != AuthCognito {
+		return fmt.Errorf("api auth provider '%s' cannot declare a default_action", p.Type)
+	}
+	return nil
+}
+
+// UnmarshalYAML satisfies the custom unmarshaler interface for go-yaml. It is
+// called automatically by the YAML unmarshal to set and check defaults.
+func (l *AuthList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var u []string
+	if err := unmarshal(&u); err != nil {
+		return err
+	}
+	for _, t := range u {
+		if _, ok := authDirectives[t]; !ok {
+			return fmt.Errorf("auth type '%s' must be one of API_KEY, AWS_IAM, AMAZON_COGNITO_USER_POOLS or OPENID_CONNECT", t)
+		}
+	}
+	*l = AuthList(u)
+	return nil
+}
+
+// Directives returns the schema directives for the authorization types, each
+// preceded by a space, ready to follow a type or field definition
+func (l AuthList) Directives() string {
+	d := ""
+	for _, t := range l {
+		d += " " + authDirectives[t]
+	}
+	return d
+}
+
+// AuthenticationType returns the default authorization type of the api
+func (a *API) AuthenticationType() string {
+	if a.Auth == nil {
+		return AuthIAM
+	}
+	return a.Auth.Default.Type
+}
+
+// AdditionalAuth returns the additional authorization providers of the api
+func (a *API) AdditionalAuth() []*AuthProvider {
+	if a.Auth == nil {
+		return nil
+	}
+	return a.Auth.Additional
+}
+
+// hasAuth reports whether the api is configured with the authorization type
+func (a *API) hasAuth(authType string) bool {
+	if a.AuthenticationType() == authType {
+		return true
+	}
+	for _, p := range a.AdditionalAuth() {
+		if p.Type == authType {
+			return true
+		}
+	}
+	return false
+}
+
+// HasAPIKey reports whether the api is authorized with an api key, so
+// requires a key to be created
+func (a *API) HasAPIKey() bool {
+	return a.hasAuth(AuthAPIKey)
+}
+
+// ConnectionDirectives returns the authorization directives for the
+// connection object of the named type. Connections are authorized the same
+// as the objects they contain.
+func (s *Schema) ConnectionDirectives(name string) string {
+	if o, ok := s.objectLookup[name]; ok {
+		return o.Auth.Directives()
+	}
+	return ""
+}
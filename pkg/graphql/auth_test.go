@@ -0,0 +1,134 @@
+package graphql_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ONSdigital/aws-appsync-generator/pkg/graphql"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestUnmarshalAPIAuth(t *testing.T) {
+	for _, c := range []struct {
+		scenario string
+		yaml     []byte
+		expected *graphql.APIAuth
+		err      error
+	}{
+		{
+			"Cognito default with additional providers",
+			[]byte("default:\n  type: AMAZON_COGNITO_USER_POOLS\n  user_pool_id: pool\nadditional:\n  - type: AWS_IAM\n  - type: OPENID_CONNECT\n    issuer: https://auth.example.com"),
+			&graphql.APIAuth{
+				Default: &graphql.AuthProvider{Type: "AMAZON_COGNITO_USER_POOLS", UserPoolID: "pool", DefaultAction: "ALLOW"},
+				Additional: []*graphql.AuthProvider{
+					{Type: "AWS_IAM"},
+					{Type: "OPENID_CONNECT", Issuer: "https://auth.example.com"},
+				},
+			},
+			nil,
+		},
+		{
+			"No default",
+			[]byte("additional:\n  - type: AWS_IAM"),
+			nil,
+			errors.New("api auth does not declare a default provider"),
+		},
+		{
+			"Bad type",
+			[]byte("default:\n  type: LAMBDA"),
+			nil,
+			errors.New("api auth provider type 'LAMBDA' must be one of API_KEY, AWS_IAM, AMAZON_COGNITO_USER_POOLS or OPENID_CONNECT"),
+		},
+		{
+			"Cognito without a user pool",
+			[]byte("default:\n  type: AMAZON_COGNITO_USER_POOLS"),
+			nil,
+			errors.New("api auth provider 'AMAZON_COGNITO_USER_POOLS' does not declare a user_pool_id"),
+		},
+		{
+			"OIDC without an issuer",
+			[]byte("default:\n  type: OPENID_CONNECT"),
+			nil,
+			errors.New("api auth provider 'OPENID_CONNECT' does not declare an issuer"),
+		},
+		{
+			"Default action on an additional provider",
+			[]byte("default:\n  type: AWS_IAM\nadditional:\n  - type: AMAZON_COGNITO_USER_POOLS\n    user_pool_id: pool\n    default_action: DENY"),
+			nil,
+			errors.New("api auth provider 'AMAZON_COGNITO_USER_POOLS' is not the default provider so cannot declare a default_action"),
+		},
+		{
+			"Duplicate provider",
+			[]byte("default:\n  type: AWS_IAM\nadditional:\n  - type: AWS_IAM"),
+			nil,
+			errors.New("api auth provider 'AWS_IAM' is declared more than once"),
+		},
+	} {
+		var a graphql.APIAuth
+		err := yaml.Unmarshal(c.yaml, &a)
+		switch c.err {
+		case nil:
+			assert.NoError(t, err)
+			assert.Equal(t, c.expected, &a, c.scenario)
+		default:
+			assert.EqualError(t, err, c.err.Error(), c.scenario)
+		}
+	}
+}
+
+func TestGenerateAuth(t *testing.T) {
+	files, err := mustCompileSchema(t, authManifest).Generate()
+	if err != nil {
+		t.Fatalf("error generating schema '%v', expected nil", err)
+	}
+
+	schema := string(files["schema.public.graphql"])
+	assert.Contains(t, schema, "type Animal @aws_iam @aws_api_key {")
+	assert.Contains(t, schema, "secret: String @aws_iam\n")
+	assert.Contains(t, schema, "type AnimalConnection @aws_iam @aws_api_key {")
+	assert.Contains(t, schema, "listAnimals(filter: AnimalFilter, limit: Int, nextToken: String): AnimalConnection! @aws_api_key\n")
+
+	api := string(files["_api_record.tf"])
+	assert.Contains(t, api, `authentication_type	= "AMAZON_COGNITO_USER_POOLS"`)
+	assert.Contains(t, api, `user_pool_id		= "eu-west-2_abc"`)
+	assert.Contains(t, api, `default_action		= "DENY"`)
+	assert.Contains(t, api, "additional_authentication_provider {\n\t\tauthentication_type	= \"API_KEY\"\n\t}")
+	assert.Contains(t, api, `resource "aws_appsync_api_key" "record"`)
+}
+
+var authManifest = []byte(`
+api:
+  create: true
+  auth:
+    default:
+      type: AMAZON_COGNITO_USER_POOLS
+      user_pool_id: eu-west-2_abc
+      default_action: DENY
+    additional:
+      - type: AWS_IAM
+      - type: API_KEY
+
+sources:
+  default:
+    name: animals
+    dynamo:
+      hash_key:
+        name: id
+
+objects:
+  - name: Animal
+    auth: [AWS_IAM, API_KEY]
+    fields:
+      - name: id
+        type: ID!
+      - name: secret
+        auth: [AWS_IAM]
+
+queries:
+  - name: listAnimals
+    auth: [API_KEY]
+    resolver:
+      action: list
+      type: [Animal]
+`)
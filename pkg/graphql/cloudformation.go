@@ -0,0 +1,157 @@
+package graphql
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// cloudFormation renders the whole api as a single cloudformation template,
+// as an alternative to the terraform output of the individual resolver and
+// source writers
+type cloudFormation struct {
+	schema    *Schema
+	resolvers []*Resolver
+}
+
+type cloudFormationResolver struct {
+	*Resolver
+	Request  string
+	Response string
+}
+
+type cloudFormationFunction struct {
+	*Function
+	Request  string
+	Response string
+}
+
+var cloudFormationFuncs = template.FuncMap{
+	"logicalID":       logicalID,
+	"yamlBlock":       yamlBlock,
+	"mappingTemplate": mappingTemplate,
+}
+
+// GenerateBytes renders the cloudformation template ready to be written to
+// an output stream
+func (cf *cloudFormation) GenerateBytes() ([]byte, error) {
+	generated := bytes.Buffer{}
+
+	t, err := template.New("cloudformation").Funcs(funcMap).Funcs(cloudFormationFuncs).Parse(cloudFormationTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	// A function managed by terraform can't be referenced from the template
+	for _, ds := range cf.schema.sortedSources() {
+		if ds.Type == "lambda" && ds.Lambda.FunctionARN == "" {
+			return nil, fmt.Errorf("lambda datasource '%s' must declare a function_arn for the %s target", ds.Name, TargetCloudFormation)
+		}
+	}
+
+	schema, err := cf.schema.GenerateBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	resolvers := make([]cloudFormationResolver, len(cf.resolvers))
+	for i, r := range cf.resolvers {
+		request, response, err := r.mappingTemplates()
+		if err != nil {
+			return nil, err
+		}
+		resolvers[i] = cloudFormationResolver{r, request, response}
+	}
+
+	functions := make([]cloudFormationFunction, len(cf.schema.Functions))
+	for i, f := range cf.schema.Functions {
+		request, response, err := f.mappingTemplates()
+		if err != nil {
+			return nil, err
+		}
+		functions[i] = cloudFormationFunction{f, request, response}
+	}
+
+	d := struct {
+		API       *API
+		Schema    string
+		Sources   []*Source
+		Functions []cloudFormationFunction
+		Resolvers []cloudFormationResolver
+	}{
+		API:       cf.schema.API,
+		Schema:    string(schema),
+		Sources:   cf.schema.sortedSources(),
+		Functions: functions,
+		Resolvers: resolvers,
+	}
+
+	if err := t.Execute(&generated, d); err != nil {
+		return nil, err
+	}
+	return generated.Bytes(), nil
+}
+
+// OutputName returns the file name to be written for the cloudformation
+// template
+func (cf *cloudFormation) OutputName() string {
+	return "template.yaml"
+}
+
+// logicalID builds a cloudformation logical resource id from the given
+// names, e.g. ("Query", "get_animal") gives "QueryGetAnimal"
+func logicalID(names ...string) string {
+	id := strings.Builder{}
+	for _, name := range names {
+		upper := true
+		for _, c := range name {
+			if !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+				upper = true
+				continue
+			}
+			if upper {
+				c = unicode.ToUpper(c)
+				upper = false
+			}
+			id.WriteRune(c)
+		}
+	}
+	return id.String()
+}
+
+// mappingTemplate renders a mapping template as a block, substituting the
+// environment where the template refers to it (e.g. in a table name). Any
+// other ${...}, e.g. a formal reference in the VTL, is escaped as ${!...} so
+// the substitution leaves it as it is
+func mappingTemplate(indent int, content string) string {
+	if !strings.Contains(content, "${Environment}") {
+		return yamlBlock(indent, content)
+	}
+	content = strings.ReplaceAll(content, "${", "${!")
+	content = strings.ReplaceAll(content, "${!Environment}", "${Environment}")
+	return "!Sub " + yamlBlock(indent, content)
+}
+
+// yamlBlock formats content as a yaml literal block scalar with each line
+// indented by the given number of spaces
+func yamlBlock(indent int, content string) string {
+	lines := strings.Split(content, "\n")
+
+	// Leading blank lines would upset the block's indentation detection
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+
+	prefix := strings.Repeat(" ", indent)
+	block := strings.Builder{}
+	block.WriteString("|")
+	for _, line := range lines {
+		block.WriteString("\n")
+		if strings.TrimSpace(line) != "" {
+			block.WriteString(prefix + line)
+		}
+	}
+	return block.String()
+}
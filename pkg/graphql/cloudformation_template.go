@@ -0,0 +1,544 @@
+package graphql
+
+var cloudFormationTemplate = `
+AWSTemplateFormatVersion: "2010-09-09"
+Description: AppSync graphql api, data sources and resolvers
+
+Parameters:
+  Environment:
+    Type: String
+    Description: Name of the environment, used to prefix the names of resources
+
+Resources:
+  GraphQLApi:
+    Type: AWS::AppSync::GraphQLApi
+    Properties:
+      Name: !Sub "${Environment}-{{ .API.Name }}"
+      AuthenticationType: {{ .API.AuthenticationType }}
+      {{- with .API.Auth }}{{ with .Default }}
+      {{- if eq .Type "AMAZON_COGNITO_USER_POOLS" }}
+      UserPoolConfig:
+        UserPoolId: "{{ .UserPoolID }}"
+        AwsRegion: {{ if .AWSRegion }}"{{ .AWSRegion }}"{{ else }}!Ref AWS::Region{{ end }}
+        {{- if .AppIDClientRegex }}
+        AppIdClientRegex: "{{ .AppIDClientRegex }}"
+        {{- end }}
+        DefaultAction: {{ .DefaultAction }}
+      {{- end }}
+      {{- if eq .Type "OPENID_CONNECT" }}
+      OpenIDConnectConfig:
+        Issuer: "{{ .Issuer }}"
+        {{- if .ClientID }}
+        ClientId: "{{ .ClientID }}"
+        {{- end }}
+        {{- if .AuthTTL }}
+        AuthTTL: {{ .AuthTTL }}
+        {{- end }}
+        {{- if .IatTTL }}
+        IatTTL: {{ .IatTTL }}
+        {{- end }}
+      {{- end }}
+      {{- end }}{{ end }}
+      {{- if .API.AdditionalAuth }}
+      AdditionalAuthenticationProviders:
+        {{- range .API.AdditionalAuth }}
+        - AuthenticationType: {{ .Type }}
+          {{- if eq .Type "AMAZON_COGNITO_USER_POOLS" }}
+          UserPoolConfig:
+            UserPoolId: "{{ .UserPoolID }}"
+            AwsRegion: {{ if .AWSRegion }}"{{ .AWSRegion }}"{{ else }}!Ref AWS::Region{{ end }}
+            {{- if .AppIDClientRegex }}
+            AppIdClientRegex: "{{ .AppIDClientRegex }}"
+            {{- end }}
+          {{- end }}
+          {{- if eq .Type "OPENID_CONNECT" }}
+          OpenIDConnectConfig:
+            Issuer: "{{ .Issuer }}"
+            {{- if .ClientID }}
+            ClientId: "{{ .ClientID }}"
+            {{- end }}
+            {{- if .AuthTTL }}
+            AuthTTL: {{ .AuthTTL }}
+            {{- end }}
+            {{- if .IatTTL }}
+            IatTTL: {{ .IatTTL }}
+            {{- end }}
+          {{- end }}
+        {{- end }}
+      {{- end }}
+      {{- if .API.Logging }}
+      LogConfig:
+        CloudWatchLogsRoleArn: !GetAtt LoggingRole.Arn
+        FieldLogLevel: {{ .API.Logging.Level }}
+        ExcludeVerboseContent: {{ .API.Logging.ExcludeVerboseContent }}
+      {{- end }}
+      Tags:
+        - Key: Environment
+          Value: !Ref Environment
+        - Key: Name
+          Value: {{ .API.Name }}
+
+  GraphQLSchema:
+    Type: AWS::AppSync::GraphQLSchema
+    Properties:
+      ApiId: !GetAtt GraphQLApi.ApiId
+      Definition: {{ yamlBlock 8 .Schema }}
+{{- if .API.HasAPIKey }}
+
+  ApiKey:
+    Type: AWS::AppSync::ApiKey
+    Properties:
+      ApiId: !GetAtt GraphQLApi.ApiId
+{{- end }}
+
+  ServiceRole:
+    Type: AWS::IAM::Role
+    Properties:
+      AssumeRolePolicyDocument:
+        Version: "2012-10-17"
+        Statement:
+          - Effect: Allow
+            Principal:
+              Service: appsync.amazonaws.com
+            Action: sts:AssumeRole
+{{- if .API.Logging }}
+
+  LoggingRole:
+    Type: AWS::IAM::Role
+    Properties:
+      AssumeRolePolicyDocument:
+        Version: "2012-10-17"
+        Statement:
+          - Effect: Allow
+            Principal:
+              Service: appsync.amazonaws.com
+            Action: sts:AssumeRole
+      ManagedPolicyArns:
+        - arn:aws:iam::aws:policy/service-role/AWSAppSyncPushToCloudWatchLogs
+{{- end }}
+{{- range .Sources }}
+{{ if eq .Type "dynamo" }}{{ template "dynamo" . }}{{ end }}
+{{- if eq .Type "sql" }}{{ template "sql" . }}{{ end }}
+{{- if eq .Type "lambda" }}{{ template "lambda" . }}{{ end }}
+{{- if eq .Type "http" }}{{ template "http" . }}{{ end }}
+{{- if eq .Type "opensearch" }}{{ template "opensearch" . }}{{ end }}
+{{- if eq .Type "eventbridge" }}{{ template "eventbridge" . }}{{ end }}
+{{- if eq .Type "none" }}{{ template "none" . }}{{ end }}
+{{- end }}
+{{- range .Functions }}
+
+  {{ logicalID .Name }}Function:
+    Type: AWS::AppSync::FunctionConfiguration
+    Properties:
+      ApiId: !GetAtt GraphQLApi.ApiId
+      Name: {{ .Name }}
+      DataSourceName: !GetAtt {{ logicalID .DataSource.Name }}DataSource.Name
+      FunctionVersion: "2018-05-29"
+      RequestMappingTemplate: {{ mappingTemplate 8 .Request }}
+      ResponseMappingTemplate: {{ mappingTemplate 8 .Response }}
+{{- end }}
+{{- range .Resolvers }}
+
+  {{ logicalID .Parent .FieldName }}Resolver:
+    Type: AWS::AppSync::Resolver
+    DependsOn: GraphQLSchema
+    Properties:
+      ApiId: !GetAtt GraphQLApi.ApiId
+      TypeName: {{ .Parent }}
+      FieldName: {{ .FieldName }}
+      {{- if .IsPipeline }}
+      Kind: PIPELINE
+      PipelineConfig:
+        Functions:
+          {{- range .Pipeline }}
+          - !GetAtt {{ logicalID . }}Function.FunctionId
+          {{- end }}
+      {{- else }}
+      DataSourceName: !GetAtt {{ logicalID .DataSource.Name }}DataSource.Name
+      {{- end }}
+      {{- if .BatchSize }}
+      MaxBatchSize: {{ .BatchSize }}
+      {{- end }}
+      {{- with .SyncConfig }}
+      SyncConfig:
+        ConflictDetection: VERSION
+        ConflictHandler: {{ .Handler }}
+        {{- if .LambdaARN }}
+        LambdaConflictHandlerConfig:
+          LambdaConflictHandlerArn: {{ .LambdaARN }}
+        {{- end }}
+      {{- end }}
+      RequestMappingTemplate: {{ mappingTemplate 8 .Request }}
+      ResponseMappingTemplate: {{ mappingTemplate 8 .Response }}
+{{- end }}
+{{ define "dynamo" }}
+  {{ logicalID .Name }}Policy:
+    Type: AWS::IAM::Policy
+    Properties:
+      PolicyName: !Sub "${Environment}-dynamo-{{ .Name }}"
+      Roles:
+        - !Ref ServiceRole
+      PolicyDocument:
+        Version: "2012-10-17"
+        Statement:
+          - Effect: Allow
+            Action:
+              - dynamodb:*
+            Resource:
+              - !GetAtt {{ logicalID .Name }}Table.Arn
+              {{- if .Dynamo.HasIndexes }}
+              - !Sub "${ {{- logicalID .Name }}Table.Arn}/index/*"
+              {{- end }}
+              {{- if .Dynamo.Conflict }}
+              - !GetAtt {{ logicalID .Name }}DeltaTable.Arn
+              {{- end }}
+          {{- if and .Dynamo.Conflict .Dynamo.Conflict.LambdaARN }}
+          - Effect: Allow
+            Action:
+              - lambda:InvokeFunction
+            Resource:
+              - {{ .Dynamo.Conflict.LambdaARN }}
+          {{- end }}
+
+  {{ logicalID .Name }}Table:
+    Type: AWS::DynamoDB::Table
+    Properties:
+      TableName: !Sub "${Environment}-{{ .Name }}"
+      {{- if .Dynamo.Provisioned }}
+      BillingMode: PROVISIONED
+      ProvisionedThroughput:
+        ReadCapacityUnits: {{ .Dynamo.Capacity.Read }}
+        WriteCapacityUnits: {{ .Dynamo.Capacity.Write }}
+      {{- else }}
+      BillingMode: PAY_PER_REQUEST
+      {{- end }}
+      {{- if .Dynamo.TableClass }}
+      TableClass: {{ .Dynamo.TableClass }}
+      {{- end }}
+      {{- if .Dynamo.DeletionProtection }}
+      DeletionProtectionEnabled: true
+      {{- end }}
+      AttributeDefinitions:
+        {{- range .Dynamo.Attributes }}
+        - AttributeName: {{ .Name }}
+          AttributeType: {{ .Type }}
+        {{- end }}
+      KeySchema:
+        - AttributeName: {{ .Dynamo.HashKey.Name }}
+          KeyType: HASH
+        {{- if .Dynamo.SortKey }}
+        - AttributeName: {{ .Dynamo.SortKey.Name }}
+          KeyType: RANGE
+        {{- end }}
+      {{- if .Dynamo.GSI }}
+      GlobalSecondaryIndexes:
+        {{- range .Dynamo.GSI }}
+        {{- template "dynamoIndex" . }}
+          {{- if $.Dynamo.Provisioned }}
+          ProvisionedThroughput:
+            ReadCapacityUnits: {{ $.Dynamo.Capacity.Read }}
+            WriteCapacityUnits: {{ $.Dynamo.Capacity.Write }}
+          {{- end }}
+        {{- end }}
+      {{- end }}
+      {{- if .Dynamo.LSI }}
+      LocalSecondaryIndexes:
+        {{- range .Dynamo.LSI }}
+        {{- template "dynamoIndex" . }}
+        {{- end }}
+      {{- end }}
+      {{- if .Dynamo.Backup }}
+      PointInTimeRecoverySpecification:
+        PointInTimeRecoveryEnabled: true
+      {{- end }}
+      {{- if .Dynamo.TTL }}
+      TimeToLiveSpecification:
+        AttributeName: {{ .Dynamo.TTL.Attribute }}
+        Enabled: true
+      {{- end }}
+      {{- with .Dynamo.Encryption }}
+      SSESpecification:
+        SSEEnabled: true
+        {{- if .KMSKeyARN }}
+        SSEType: KMS
+        KMSMasterKeyId: "{{ .KMSKeyARN }}"
+        {{- end }}
+      {{- end }}
+      {{- if .Dynamo.Stream }}
+      StreamSpecification:
+        StreamViewType: {{ .Dynamo.Stream }}
+      {{- end }}
+      Tags:
+        - Key: Environment
+          Value: !Ref Environment
+        - Key: Name
+          Value: {{ .Name }}
+        {{- range $k, $v := .Dynamo.Tags }}
+        - Key: {{ printf "%q" $k }}
+          Value: {{ printf "%q" $v }}
+        {{- end }}
+
+  {{ logicalID .Name }}DataSource:
+    Type: AWS::AppSync::DataSource
+    Properties:
+      ApiId: !GetAtt GraphQLApi.ApiId
+      Name: !Sub "${Environment}_{{ .Name }}"
+      Type: AMAZON_DYNAMODB
+      ServiceRoleArn: !GetAtt ServiceRole.Arn
+      DynamoDBConfig:
+        TableName: !Ref {{ logicalID .Name }}Table
+        AwsRegion: !Ref AWS::Region
+        {{- with .Dynamo.Conflict }}
+        Versioned: true
+        DeltaSyncConfig:
+          BaseTableTTL: "{{ .BaseTableTTL }}"
+          DeltaSyncTableName: !Ref {{ logicalID $.Name }}DeltaTable
+          DeltaSyncTableTTL: "{{ .DeltaSyncTableTTL }}"
+        {{- end }}
+{{- if .Dynamo.Conflict }}
+
+  {{ logicalID .Name }}DeltaTable:
+    Type: AWS::DynamoDB::Table
+    Properties:
+      TableName: !Sub "${Environment}-{{ .Name }}-delta"
+      BillingMode: PAY_PER_REQUEST
+      AttributeDefinitions:
+        - AttributeName: ds_pk
+          AttributeType: S
+        - AttributeName: ds_sk
+          AttributeType: S
+      KeySchema:
+        - AttributeName: ds_pk
+          KeyType: HASH
+        - AttributeName: ds_sk
+          KeyType: RANGE
+      TimeToLiveSpecification:
+        AttributeName: _ttl
+        Enabled: true
+      Tags:
+        - Key: Environment
+          Value: !Ref Environment
+        - Key: Name
+          Value: {{ .Name }}-delta
+{{- end }}
+{{- range .Dynamo.ScalableDimensions }}
+
+  {{ logicalID $.Name }}{{ .Dimension }}ScalableTarget:
+    Type: AWS::ApplicationAutoScaling::ScalableTarget
+    Properties:
+      MinCapacity: {{ .Min }}
+      MaxCapacity: {{ .Max }}
+      ResourceId: !Sub "table/${ {{- logicalID $.Name }}Table}"
+      ScalableDimension: dynamodb:table:{{ .Dimension }}CapacityUnits
+      ServiceNamespace: dynamodb
+
+  {{ logicalID $.Name }}{{ .Dimension }}ScalingPolicy:
+    Type: AWS::ApplicationAutoScaling::ScalingPolicy
+    Properties:
+      PolicyName: !Sub "${Environment}-{{ $.Name }}-{{ .Suffix }}"
+      PolicyType: TargetTrackingScaling
+      ScalingTargetId: !Ref {{ logicalID $.Name }}{{ .Dimension }}ScalableTarget
+      TargetTrackingScalingPolicyConfiguration:
+        TargetValue: {{ .Target }}
+        PredefinedMetricSpecification:
+          PredefinedMetricType: DynamoDB{{ .Dimension }}CapacityUtilization
+{{- end }}
+{{- end }}
+
+{{- define "sql" }}
+  {{ logicalID .Name }}Policy:
+    Type: AWS::IAM::Policy
+    Properties:
+      PolicyName: !Sub "${Environment}-sql-{{ .Name }}"
+      Roles:
+        - !Ref ServiceRole
+      PolicyDocument:
+        Version: "2012-10-17"
+        Statement:
+          - Effect: Allow
+            Action:
+              - rds-data:BatchExecuteStatement
+              - rds-data:BeginTransaction
+              - rds-data:CommitTransaction
+              - rds-data:ExecuteStatement
+              - rds-data:RollbackTransaction
+            Resource:
+              - "{{ .SQL.ClusterARN }}"
+              - "{{ .SQL.ClusterARN }}:*"
+          - Effect: Allow
+            Action:
+              - secretsmanager:GetSecretValue
+            Resource:
+              - "{{ .SQL.SecretARN }}"
+              - "{{ .SQL.SecretARN }}:*"
+
+  {{ logicalID .Name }}DataSource:
+    Type: AWS::AppSync::DataSource
+    Properties:
+      ApiId: !GetAtt GraphQLApi.ApiId
+      Name: !Sub "${Environment}_{{ .Name }}"
+      Type: RELATIONAL_DATABASE
+      ServiceRoleArn: !GetAtt ServiceRole.Arn
+      RelationalDatabaseConfig:
+        RelationalDatabaseSourceType: RDS_HTTP_ENDPOINT
+        RdsHttpEndpointConfig:
+          AwsRegion: !Ref AWS::Region
+          DbClusterIdentifier: "{{ .SQL.ClusterARN }}"
+          AwsSecretStoreArn: "{{ .SQL.SecretARN }}"
+          DatabaseName: "{{ .SQL.Database }}"
+{{- end }}
+
+{{- define "dynamoIndex" }}
+        - IndexName: {{ .Name }}
+          KeySchema:
+            - AttributeName: {{ .HashKey.Name }}
+              KeyType: HASH
+            {{- if .SortKey }}
+            - AttributeName: {{ .SortKey.Name }}
+              KeyType: RANGE
+            {{- end }}
+          Projection:
+            ProjectionType: {{ .Projection }}
+            {{- if .NonKeyAttributes }}
+            NonKeyAttributes:
+              {{- range .NonKeyAttributes }}
+              - {{ . }}
+              {{- end }}
+            {{- end }}
+{{- end }}
+
+{{- define "lambda" }}
+  {{ logicalID .Name }}Policy:
+    Type: AWS::IAM::Policy
+    Properties:
+      PolicyName: !Sub "${Environment}-lambda-{{ .Name }}"
+      Roles:
+        - !Ref ServiceRole
+      PolicyDocument:
+        Version: "2012-10-17"
+        Statement:
+          - Effect: Allow
+            Action:
+              - lambda:InvokeFunction
+            Resource:
+              - "{{ .Lambda.FunctionARN }}"
+              - "{{ .Lambda.FunctionARN }}:*"
+
+  {{ logicalID .Name }}DataSource:
+    Type: AWS::AppSync::DataSource
+    Properties:
+      ApiId: !GetAtt GraphQLApi.ApiId
+      Name: !Sub "${Environment}_{{ .Name }}"
+      Type: AWS_LAMBDA
+      ServiceRoleArn: !GetAtt ServiceRole.Arn
+      LambdaConfig:
+        LambdaFunctionArn: "{{ .Lambda.FunctionARN }}"
+{{- end }}
+
+{{- define "http" }}
+  {{- with .HTTP.IAM }}
+  {{ logicalID $.Name }}Policy:
+    Type: AWS::IAM::Policy
+    Properties:
+      PolicyName: !Sub "${Environment}-http-{{ $.Name }}"
+      Roles:
+        - !Ref ServiceRole
+      PolicyDocument:
+        Version: "2012-10-17"
+        Statement:
+          - Effect: Allow
+            Action:
+              {{- range .Actions }}
+              - {{ . }}
+              {{- end }}
+            Resource:
+              {{- range .Resources }}
+              - "{{ . }}"
+              {{- end }}
+{{ end }}
+  {{ logicalID .Name }}DataSource:
+    Type: AWS::AppSync::DataSource
+    Properties:
+      ApiId: !GetAtt GraphQLApi.ApiId
+      Name: !Sub "${Environment}_{{ .Name }}"
+      Type: HTTP
+      {{- if .HTTP.IAM }}
+      ServiceRoleArn: !GetAtt ServiceRole.Arn
+      {{- end }}
+      HttpConfig:
+        Endpoint: "{{ .HTTP.Endpoint }}"
+        {{- if .HTTP.IAM }}
+        AuthorizationConfig:
+          AuthorizationType: AWS_IAM
+          AwsIamConfig:
+            SigningRegion: "{{ .HTTP.IAM.SigningRegion }}"
+            SigningServiceName: "{{ .HTTP.IAM.SigningService }}"
+        {{- end }}
+{{- end }}
+
+{{- define "opensearch" }}
+  {{ logicalID .Name }}Policy:
+    Type: AWS::IAM::Policy
+    Properties:
+      PolicyName: !Sub "${Environment}-opensearch-{{ .Name }}"
+      Roles:
+        - !Ref ServiceRole
+      PolicyDocument:
+        Version: "2012-10-17"
+        Statement:
+          - Effect: Allow
+            Action:
+              - es:ESHttpGet
+              - es:ESHttpPost
+            Resource:
+              - "{{ .OpenSearch.DomainARN }}"
+              - "{{ .OpenSearch.DomainARN }}/*"
+
+  {{ logicalID .Name }}DataSource:
+    Type: AWS::AppSync::DataSource
+    Properties:
+      ApiId: !GetAtt GraphQLApi.ApiId
+      Name: !Sub "${Environment}_{{ .Name }}"
+      Type: AMAZON_OPENSEARCH_SERVICE
+      ServiceRoleArn: !GetAtt ServiceRole.Arn
+      OpenSearchServiceConfig:
+        Endpoint: "{{ .OpenSearch.Endpoint }}"
+        AwsRegion: {{ if .OpenSearch.Region }}"{{ .OpenSearch.Region }}"{{ else }}!Ref AWS::Region{{ end }}
+{{- end }}
+
+{{- define "eventbridge" }}
+  {{ logicalID .Name }}Policy:
+    Type: AWS::IAM::Policy
+    Properties:
+      PolicyName: !Sub "${Environment}-eventbridge-{{ .Name }}"
+      Roles:
+        - !Ref ServiceRole
+      PolicyDocument:
+        Version: "2012-10-17"
+        Statement:
+          - Effect: Allow
+            Action:
+              - events:PutEvents
+            Resource:
+              - "{{ .EventBridge.BusARN }}"
+
+  {{ logicalID .Name }}DataSource:
+    Type: AWS::AppSync::DataSource
+    Properties:
+      ApiId: !GetAtt GraphQLApi.ApiId
+      Name: !Sub "${Environment}_{{ .Name }}"
+      Type: AMAZON_EVENTBRIDGE
+      ServiceRoleArn: !GetAtt ServiceRole.Arn
+      EventBridgeConfig:
+        EventBusArn: "{{ .EventBridge.BusARN }}"
+{{- end }}
+
+{{- define "none" }}
+  {{ logicalID .Name }}DataSource:
+    Type: AWS::AppSync::DataSource
+    Properties:
+      ApiId: !GetAtt GraphQLApi.ApiId
+      Name: !Sub "${Environment}_{{ .Name }}"
+      Type: NONE
+{{- end -}}
+`
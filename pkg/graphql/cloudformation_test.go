@@ -0,0 +1,190 @@
+package graphql_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ONSdigital/aws-appsync-generator/pkg/graphql"
+	"github.com/stretchr/testify/assert"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// cloudFormationResource is a resource of the generated template, with the
+// tags of its intrinsic functions kept apart from the values of its properties
+type cloudFormationResource struct {
+	Type       string
+	Properties map[string]interface{}
+	Tags       map[string]string
+}
+
+func (r *cloudFormationResource) UnmarshalYAML(node *yamlv3.Node) error {
+	var resource struct {
+		Type       string                 `yaml:"Type"`
+		Properties map[string]yamlv3.Node `yaml:"Properties"`
+	}
+	if err := node.Decode(&resource); err != nil {
+		return err
+	}
+
+	r.Type = resource.Type
+	r.Properties = map[string]interface{}{}
+	r.Tags = map[string]string{}
+	for name, property := range resource.Properties {
+		var value interface{}
+		if err := property.Decode(&value); err != nil {
+			return err
+		}
+		r.Properties[name] = value
+		r.Tags[name] = property.Tag
+	}
+	return nil
+}
+
+// mustGenerateCloudFormation generates the manifest for the cloudformation
+// target and parses the resources of its template by logical id
+func mustGenerateCloudFormation(t *testing.T, manifest []byte) map[string]cloudFormationResource {
+	original := graphql.Target
+	graphql.Target = graphql.TargetCloudFormation
+	defer func() { graphql.Target = original }()
+
+	var template struct {
+		Resources map[string]cloudFormationResource `yaml:"Resources"`
+	}
+	if err := yamlv3.Unmarshal(mustGenerate(t, manifest)["template.yaml"], &template); err != nil {
+		t.Fatalf("generated template is not valid yaml: %v", err)
+	}
+	return template.Resources
+}
+
+func TestCloudFormationTarget(t *testing.T) {
+	original := graphql.Target
+	graphql.Target = graphql.TargetCloudFormation
+	defer func() { graphql.Target = original }()
+
+	files, err := mustCompileSchema(t, builtInTemplatesManifest).Generate()
+	if err != nil {
+		t.Fatalf("error generating schema '%v', expected nil", err)
+	}
+
+	names := []string{}
+	for name := range files {
+		names = append(names, name)
+	}
+	assert.ElementsMatch(t, []string{"schema.public.graphql", "template.yaml"}, names)
+
+	var template struct {
+		Resources map[string]struct {
+			Type string `yaml:"Type"`
+		} `yaml:"Resources"`
+	}
+	if err := yamlv3.Unmarshal(files["template.yaml"], &template); err != nil {
+		t.Fatalf("generated template is not valid yaml: %v", err)
+	}
+
+	types := map[string]string{}
+	for id, r := range template.Resources {
+		types[id] = r.Type
+	}
+	assert.Equal(t, map[string]string{
+		"GraphQLApi":                       "AWS::AppSync::GraphQLApi",
+		"GraphQLSchema":                    "AWS::AppSync::GraphQLSchema",
+		"ServiceRole":                      "AWS::IAM::Role",
+		"PeoplePolicy":                     "AWS::IAM::Policy",
+		"PeopleTable":                      "AWS::DynamoDB::Table",
+		"PeopleDataSource":                 "AWS::AppSync::DataSource",
+		"PlacesPolicy":                     "AWS::IAM::Policy",
+		"PlacesTable":                      "AWS::DynamoDB::Table",
+		"PlacesDataSource":                 "AWS::AppSync::DataSource",
+		"ThingsPolicy":                     "AWS::IAM::Policy",
+		"ThingsDataSource":                 "AWS::AppSync::DataSource",
+		"QueryGetCorrespondenceResolver":   "AWS::AppSync::Resolver",
+		"QueryListPeopleResolver":          "AWS::AppSync::Resolver",
+		"QueryListCorrespondenceResolver":  "AWS::AppSync::Resolver",
+		"MutationUpdatePersonResolver":     "AWS::AppSync::Resolver",
+		"CorrespondenceRecipientsResolver": "AWS::AppSync::Resolver",
+	}, types)
+}
+
+func TestCloudFormationTargetLambda(t *testing.T) {
+	original := graphql.Target
+	graphql.Target = graphql.TargetCloudFormation
+	defer func() { graphql.Target = original }()
+
+	// The vets function is only referenced by its terraform resource
+	_, err := mustCompileSchema(t, lambdaManifest).Generate()
+	assert.Error(t, err)
+
+	manifest := bytes.Replace(lambdaManifest, []byte("function: vet_records"), []byte("function_arn: arn:aws:lambda:eu-west-2:123456789012:function:vets"), 1)
+	files, err := mustCompileSchema(t, manifest).Generate()
+	if err != nil {
+		t.Fatalf("error generating schema '%v', expected nil", err)
+	}
+	template := string(files["template.yaml"])
+	assert.Contains(t, template, "LambdaFunctionArn: \"arn:aws:lambda:eu-west-2:123456789012:function:vets\"")
+	assert.Contains(t, template, "MaxBatchSize: 10")
+}
+
+func TestCloudFormationMappingTemplateSubstitution(t *testing.T) {
+	original := graphql.Target
+	graphql.Target = graphql.TargetCloudFormation
+	defer func() { graphql.Target = original }()
+
+	dir, err := ioutil.TempDir("", "templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.MkdirAll(filepath.Join(dir, "dynamo", "response"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	override := `{{define "response" -}}$util.toJson(${ctx.result.data}["{{ tableName .DataSource.Name }}"]){{- end}}`
+	if err := ioutil.WriteFile(filepath.Join(dir, "dynamo", "response", "batch-get.tmpl"), []byte(override), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	originalPath := graphql.TemplatesPath
+	graphql.TemplatesPath = dir
+	defer func() { graphql.TemplatesPath = originalPath }()
+
+	files, err := mustCompileSchema(t, []byte(`
+sources:
+  default:
+    name: animals
+    dynamo:
+      hash_key:
+        name: id
+objects:
+  - name: Animal
+    fields:
+      - name: id
+        type: ID!
+queries:
+  - name: getAnimals
+    resolver:
+      action: batch-get
+      type: [Animal]
+      keyFields:
+        - name: id
+`)).Generate()
+	if err != nil {
+		t.Fatalf("error generating schema '%v', expected nil", err)
+	}
+
+	var template struct {
+		Resources map[string]struct {
+			Properties map[string]yamlv3.Node `yaml:"Properties"`
+		} `yaml:"Resources"`
+	}
+	if err := yamlv3.Unmarshal(files["template.yaml"], &template); err != nil {
+		t.Fatalf("generated template is not valid yaml: %v", err)
+	}
+
+	// Only the environment is substituted, the VTL reference is escaped
+	response := template.Resources["QueryGetAnimalsResolver"].Properties["ResponseMappingTemplate"]
+	assert.Equal(t, "!Sub", response.Tag)
+	assert.Equal(t, `$util.toJson(${!ctx.result.data}["${Environment}-animals"])`+"\n", response.Value)
+}
@@ -0,0 +1,92 @@
+package graphql
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// Diff compares generated files (as returned by `Generate`) against the
+// current contents of `GeneratedFilesPath` and returns a unified diff of any
+// differences. Files in the output path that would no longer be generated
+// are shown as removed. An empty diff means the output path is up to date.
+func Diff(files map[string][]byte) (string, error) {
+	existing := map[string][]byte{}
+
+	entries, err := ioutil.ReadDir(GeneratedFilesPath)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		content, err := ioutil.ReadFile(filepath.Join(GeneratedFilesPath, e.Name()))
+		if err != nil {
+			return "", err
+		}
+		existing[e.Name()] = content
+	}
+
+	names := []string{}
+	for name := range files {
+		names = append(names, name)
+	}
+	for name := range existing {
+		if _, ok := files[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	diff := strings.Builder{}
+	for _, name := range names {
+		current, inExisting := existing[name]
+		generated, inFiles := files[name]
+		if inExisting && inFiles && bytes.Equal(current, generated) {
+			continue
+		}
+
+		path := filepath.Join(GeneratedFilesPath, name)
+		ud := difflib.UnifiedDiff{
+			FromFile: path,
+			ToFile:   path,
+			Context:  3,
+		}
+		if inExisting {
+			ud.A = splitLines(current)
+		} else {
+			ud.FromFile = os.DevNull
+		}
+		if inFiles {
+			ud.B = splitLines(generated)
+		} else {
+			ud.ToFile = os.DevNull
+		}
+
+		text, err := difflib.GetUnifiedDiffString(ud)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to diff '%s'", path)
+		}
+		diff.WriteString(text)
+	}
+	return diff.String(), nil
+}
+
+// splitLines splits content into newline terminated lines. Unlike
+// difflib.SplitLines, content that already ends in a newline does not gain
+// an extra empty line.
+func splitLines(content []byte) []string {
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+	lines[len(lines)-1] += "\n"
+	return lines
+}
@@ -0,0 +1,57 @@
+package graphql_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ONSdigital/aws-appsync-generator/pkg/graphql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "generated")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	original := graphql.GeneratedFilesPath
+	graphql.GeneratedFilesPath = dir
+	defer func() { graphql.GeneratedFilesPath = original }()
+
+	for name, content := range map[string]string{
+		"unchanged.tf": "same\n",
+		"changed.tf":   "one\ntwo\n",
+		"removed.tf":   "gone\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d, err := graphql.Diff(map[string][]byte{
+		"unchanged.tf": []byte("same\n"),
+		"changed.tf":   []byte("one\nthree\n"),
+		"added.tf":     []byte("new\n"),
+	})
+	assert.NoError(t, err)
+
+	added := filepath.Join(dir, "added.tf")
+	changed := filepath.Join(dir, "changed.tf")
+	removed := filepath.Join(dir, "removed.tf")
+	assert.Equal(t, ""+
+		"--- "+os.DevNull+"\n+++ "+added+"\n@@ -0,0 +1 @@\n+new\n"+
+		"--- "+changed+"\n+++ "+changed+"\n@@ -1,2 +1,2 @@\n one\n-two\n+three\n"+
+		"--- "+removed+"\n+++ "+os.DevNull+"\n@@ -1 +0,0 @@\n-gone\n",
+		d)
+
+	d, err = graphql.Diff(map[string][]byte{
+		"unchanged.tf": []byte("same\n"),
+		"changed.tf":   []byte("one\ntwo\n"),
+		"removed.tf":   []byte("gone\n"),
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, d)
+}
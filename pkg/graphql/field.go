@@ -11,6 +11,22 @@ var reLegalFields = regexp.MustCompile(`(ID|String|Int|Float|Boolean|AWS(Date(Ti
 
 const defaultFieldType = "String"
 
+// Constants for the values generated for fields
+const (
+	GeneratedUUID      = "uuid"
+	GeneratedULID      = "ulid"
+	GeneratedCreatedAt = "createdAt"
+	GeneratedUpdatedAt = "updatedAt"
+)
+
+// generatedTypes lists the types of field each generated value can be stored in
+var generatedTypes = map[string][]string{
+	GeneratedUUID:      {"ID", "String"},
+	GeneratedULID:      {"ID", "String"},
+	GeneratedCreatedAt: {"AWSDateTime", "String"},
+	GeneratedUpdatedAt: {"AWSDateTime", "String"},
+}
+
 // Custom errors
 var (
 	ErrFieldHasNoName            = errors.New("fields must have a 'name' attribute")
@@ -49,6 +65,24 @@ type (
 		// (Optional) Type to be used when this field is included in an input
 		// object definition if it differs from the main defined type
 		InputType *FieldType
+
+		// (Optional) Authorization types allowed to access the field
+		Auth AuthList
+
+		// (Optional) Value generated by the server rather than given by the
+		// client - uuid, ulid, createdAt or updatedAt
+		Generated string
+
+		// (Optional) Marks the field as the time the object expires. It is
+		// stored in the ttl attribute of the object's dynamo source
+		TTL *FieldTTL
+	}
+
+	// FieldTTL marks the field holding the expiry time of an object
+	FieldTTL struct {
+		// (Optional) Seconds an object lives for where it's created without
+		// an expiry time. Where not set, such objects never expire
+		DefaultLifetime int `yaml:"defaultLifetime"`
 	}
 )
 
@@ -110,6 +144,9 @@ func (f *Field) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		Resolver  *Resolver  `yaml:"resolver"`
 		Type      *FieldType `yaml:"type"`
 		InputType *FieldType `yaml:"inputType"`
+		Auth      AuthList   `yaml:"auth"`
+		Generated string     `yaml:"generated"`
+		TTL       *FieldTTL  `yaml:"ttl"`
 	}
 	if err := unmarshal(&u); err != nil {
 		return err
@@ -123,6 +160,9 @@ func (f *Field) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	f.Type = u.Type
 	f.InputType = u.InputType
 	f.Parent = u.Parent
+	f.Auth = u.Auth
+	f.Generated = u.Generated
+	f.TTL = u.TTL
 
 	if u.Resolver != nil {
 		f.Type = u.Resolver.Type
@@ -137,5 +177,66 @@ func (f *Field) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		}
 	}
 
+	if f.Generated != "" {
+		if err := f.checkGenerated(); err != nil {
+			return err
+		}
+	}
+
+	if f.TTL != nil {
+		return f.checkTTL()
+	}
+
 	return nil
 }
+
+// checkTTL checks the field can hold the expiry time of an object
+func (f *Field) checkTTL() error {
+	switch {
+	case f.Resolver != nil:
+		return fmt.Errorf("field '%s' cannot be both the ttl and resolved", f.Name)
+	case f.Generated != "":
+		return fmt.Errorf("field '%s' cannot be both the ttl and generated", f.Name)
+	case f.Type.IsList || (f.Type.Name != "AWSDateTime" && f.Type.Name != "AWSTimestamp"):
+		return fmt.Errorf("field '%s' holding the ttl must be of type AWSDateTime or AWSTimestamp", f.Name)
+	case f.TTL.DefaultLifetime < 0:
+		return fmt.Errorf("field '%s' ttl defaultLifetime must be positive", f.Name)
+	}
+	return nil
+}
+
+// checkGenerated checks the generated value can be stored in the field
+func (f *Field) checkGenerated() error {
+	types, ok := generatedTypes[f.Generated]
+	switch {
+	case !ok:
+		return fmt.Errorf("field '%s' generated value '%s' must be one of uuid, ulid, createdAt or updatedAt", f.Name, f.Generated)
+	case f.Resolver != nil:
+		return fmt.Errorf("field '%s' cannot be both generated and resolved", f.Name)
+	case f.Type.IsList:
+		return fmt.Errorf("field '%s' generated by %s cannot be a list", f.Name, f.Generated)
+	}
+	for _, t := range types {
+		if f.Type.Name == t {
+			return nil
+		}
+	}
+	return fmt.Errorf("field '%s' generated by %s must be of type %s", f.Name, f.Generated, strings.Join(types, " or "))
+}
+
+// generatesID reports whether the field's value is a generated identifier
+func (f *Field) generatesID() bool {
+	return f.Generated == GeneratedUUID || f.Generated == GeneratedULID
+}
+
+// GeneratedValue returns the VTL expression generating the value of the
+// field. Timestamps refer to $now, so every timestamp of a record is the same
+func (f *Field) GeneratedValue() string {
+	switch f.Generated {
+	case GeneratedUUID:
+		return "$util.autoId()"
+	case GeneratedULID:
+		return "$util.autoUlid()"
+	}
+	return "$now"
+}
@@ -1,6 +1,7 @@
 package graphql_test
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/ONSdigital/aws-appsync-generator/pkg/graphql"
@@ -96,6 +97,50 @@ func TestUnmarshalField(t *testing.T) {
 			},
 			nil,
 		},
+		{
+			"Field with generated value",
+			[]byte("name: createdAt\ntype: AWSDateTime\ngenerated: createdAt"),
+			&graphql.Field{
+				Name:      "createdAt",
+				Type:      &graphql.FieldType{"AWSDateTime", false, false},
+				Generated: graphql.GeneratedCreatedAt,
+			},
+			nil,
+		},
+		{
+			"Field with unknown generated value",
+			[]byte("name: id\ntype: ID!\ngenerated: sequence"),
+			nil,
+			errors.New("field 'id' generated value 'sequence' must be one of uuid, ulid, createdAt or updatedAt"),
+		},
+		{
+			"Field with generated value of the wrong type",
+			[]byte("name: id\ntype: Int\ngenerated: ulid"),
+			nil,
+			errors.New("field 'id' generated by ulid must be of type ID or String"),
+		},
+		{
+			"Field with generated list",
+			[]byte("name: ids\ntype: [ID]\ngenerated: uuid"),
+			nil,
+			errors.New("field 'ids' generated by uuid cannot be a list"),
+		},
+		{
+			"Field holding the ttl",
+			[]byte("name: expiresAt\ntype: AWSTimestamp\nttl:\n  defaultLifetime: 60"),
+			&graphql.Field{
+				Name: "expiresAt",
+				Type: &graphql.FieldType{"AWSTimestamp", false, false},
+				TTL:  &graphql.FieldTTL{DefaultLifetime: 60},
+			},
+			nil,
+		},
+		{
+			"Field holding the ttl of the wrong type",
+			[]byte("name: expiresAt\ntype: Int\nttl: {}"),
+			nil,
+			errors.New("field 'expiresAt' holding the ttl must be of type AWSDateTime or AWSTimestamp"),
+		},
 		// {
 		// 	"Field should error with type and resolver",
 		// 	[]byte("name: bad\ntype: String\nresolver:\n  action: get\n"),
@@ -0,0 +1,159 @@
+package graphql
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+var reFunctionName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+type (
+	// Function is a reusable appsync function, run as one step of a pipeline
+	// resolver
+	//
+	//   Function {
+	//   - name: <function_name>
+	//     source: <source_name> # optional
+	//     request: <request_mapping_template>
+	//     response: <response_mapping_template>
+	//   - name: <function_name>
+	//     source: <source_name> # optional
+	//     action: <get|list|insert|update|delete...>
+	//     type: <return_type>
+	//     keyFields: ...
+	//   ...
+	//   }
+	//
+	Function struct {
+		Name string `yaml:"name"`
+
+		// Name of the datasource the function uses. Defaults to the
+		// `default` source
+		SourceKey string `yaml:"source"`
+
+		// Mapping templates of the function. Must be declared unless the
+		// function performs one of the built in actions
+		Request  string `yaml:"request"`
+		Response string `yaml:"response"`
+
+		// The built in action to perform, as for a resolver
+		Action    string     `yaml:"action"`
+		Type      *FieldType `yaml:"type"`
+		KeyFields []*Field   `yaml:"keyFields"`
+
+		// Set automatically
+		DataSource *Source
+		API        *API
+
+		// Performs the built in action
+		resolver *Resolver
+	}
+
+	unmarshalFunction Function
+)
+
+// UnmarshalYAML satisfies the custom unmarshaler interface for go-yaml. It is
+// called automatically by the YAML unmarshal.
+func (f *Function) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var u unmarshalFunction
+	if err := unmarshal(&u); err != nil {
+		return err
+	}
+
+	*f = Function(u)
+
+	if !reFunctionName.MatchString(f.Name) {
+		return fmt.Errorf("function name '%s' must contain only letters, digits and underscores", f.Name)
+	}
+
+	templates := f.Request != "" || f.Response != ""
+	switch {
+	case f.Action != "" && templates:
+		return fmt.Errorf("function '%s' cannot declare both an action and mapping templates", f.Name)
+	case f.Action == "" && (f.Request == "" || f.Response == ""):
+		return fmt.Errorf("function '%s' must declare either an action or request and response mapping templates", f.Name)
+	case f.Action != "" && f.Type == nil:
+		return fmt.Errorf("function '%s' does not declare the type returned by its action", f.Name)
+	}
+	return nil
+}
+
+// mappingTemplates returns the request and response mapping templates of
+// the function, rendering those of the built in action where there is one
+func (f *Function) mappingTemplates() (string, string, error) {
+	if f.resolver == nil {
+		return strings.TrimSpace(f.Request), strings.TrimSpace(f.Response), nil
+	}
+	return f.resolver.mappingTemplates()
+}
+
+// GenerateBytes renders the function ready to be written to an output stream
+func (f *Function) GenerateBytes() ([]byte, error) {
+	generated := bytes.Buffer{}
+
+	request, response, err := f.mappingTemplates()
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := template.New(f.Name).Funcs(funcMap).Parse(functionTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	d := struct {
+		*Function
+		Request  string
+		Response string
+	}{f, request, response}
+
+	if err := t.Execute(&generated, d); err != nil {
+		return nil, err
+	}
+	return generated.Bytes(), nil
+}
+
+// OutputName returns the file name to be written for the function
+func (f *Function) OutputName() string {
+	return strings.ToLower(fmt.Sprintf("_function_%s.tf", f.Name))
+}
+
+// function returns the named function, or nil if it doesn't exist
+func (s *Schema) function(name string) *Function {
+	for _, f := range s.Functions {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// prepareFunctions links each function to its data source and creates the
+// resolver performing any built in action
+func (s *Schema) prepareFunctions() error {
+	for _, f := range s.Functions {
+		r := &Resolver{
+			Action:     f.Action,
+			Type:       f.Type,
+			KeyFields:  f.KeyFields,
+			SourceKey:  f.SourceKey,
+			ArgsSource: "args",
+			Parent:     "Function",
+			FieldName:  f.Name,
+		}
+		if err := setDataSource(r, s); err != nil {
+			return fmt.Errorf("function '%s' has unknown data source '%s'", f.Name, f.SourceKey)
+		}
+		setObject(r, s)
+
+		f.DataSource = r.DataSource
+		f.API = s.API
+		if f.Action != "" {
+			f.resolver = r
+		}
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package graphql
+
+var functionTemplate = `
+resource "aws_appsync_function" "{{.Name}}" {
+	api_id                    = aws_appsync_graphql_api.{{.API.Name}}.id
+	data_source               = aws_appsync_datasource.{{ .DataSource.Name }}.name
+	name                      = "{{.Name}}"
+	request_mapping_template  = <<EOF
+{{ .Request }}
+EOF
+	response_mapping_template = <<EOF
+{{ .Response }}
+EOF
+}
+`
@@ -0,0 +1,134 @@
+package graphql_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ONSdigital/aws-appsync-generator/pkg/graphql"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestUnmarshalFunction(t *testing.T) {
+	for _, c := range []struct {
+		scenario string
+		yaml     []byte
+		err      error
+	}{
+		{
+			"Mapping templates",
+			[]byte("name: authorise\nrequest: '{}'\nresponse: $util.toJson($ctx.result)"),
+			nil,
+		},
+		{
+			"Built in action",
+			[]byte("name: fetch\naction: get\ntype: Animal"),
+			nil,
+		},
+		{
+			"Bad name",
+			[]byte("name: fetch-animal\naction: get\ntype: Animal"),
+			errors.New("function name 'fetch-animal' must contain only letters, digits and underscores"),
+		},
+		{
+			"Action and templates",
+			[]byte("name: fetch\naction: get\ntype: Animal\nrequest: '{}'"),
+			errors.New("function 'fetch' cannot declare both an action and mapping templates"),
+		},
+		{
+			"Missing response template",
+			[]byte("name: authorise\nrequest: '{}'"),
+			errors.New("function 'authorise' must declare either an action or request and response mapping templates"),
+		},
+		{
+			"Action without a type",
+			[]byte("name: fetch\naction: get"),
+			errors.New("function 'fetch' does not declare the type returned by its action"),
+		},
+	} {
+		var f graphql.Function
+		err := yaml.Unmarshal(c.yaml, &f)
+		switch c.err {
+		case nil:
+			assert.NoError(t, err, c.scenario)
+		default:
+			assert.EqualError(t, err, c.err.Error(), c.scenario)
+		}
+	}
+}
+
+func TestGeneratePipeline(t *testing.T) {
+	files, err := mustCompileSchema(t, pipelineManifest).Generate()
+	if err != nil {
+		t.Fatalf("error generating schema '%v', expected nil", err)
+	}
+
+	resolver := string(files["_query_getanimal.tf"])
+	assert.Contains(t, resolver, `kind              = "PIPELINE"`)
+	assert.NotContains(t, resolver, "data_source")
+	assert.Contains(t, resolver, "aws_appsync_function.authorise.function_id,\n\t\t\taws_appsync_function.fetchAnimal.function_id,")
+	assert.Contains(t, resolver, "request_template  = <<EOF\n{}\nEOF")
+	assert.Contains(t, resolver, "response_template = <<EOF\n$util.toJson($ctx.prev.result)\nEOF")
+
+	authorise := string(files["_function_authorise.tf"])
+	assert.Contains(t, authorise, "data_source               = aws_appsync_datasource.keepers.name")
+	assert.Contains(t, authorise, "$util.unauthorized()")
+
+	fetch := string(files["_function_fetchanimal.tf"])
+	assert.Contains(t, fetch, "data_source               = aws_appsync_datasource.animals.name")
+	assert.Contains(t, fetch, `"operation": "GetItem"`)
+
+	assert.Contains(t, string(files["schema.public.graphql"]), "getAnimal(id:ID): Animal")
+}
+
+var pipelineManifest = []byte(`
+sources:
+  default:
+    name: animals
+    dynamo:
+      hash_key:
+        name: id
+  keepers:
+    name: keepers
+    dynamo:
+      hash_key:
+        name: keeperId
+
+objects:
+  - name: Animal
+    fields:
+      - name: id
+        type: ID!
+
+functions:
+  - name: authorise
+    source: keepers
+    request: |
+      {
+          "version": "2018-05-29",
+          "operation": "GetItem",
+          "key": {
+              "keeperId": $util.dynamodb.toDynamoDBJson($ctx.identity.username)
+          }
+      }
+    response: |
+      #if( $util.isNull($ctx.result) )
+          $util.unauthorized()
+      #end
+      $util.toJson($ctx.result)
+  - name: fetchAnimal
+    action: get
+    type: Animal
+    keyFields:
+      - name: id
+        type: ID
+
+queries:
+  - name: getAnimal
+    resolver:
+      type: Animal
+      keyFields:
+        - name: id
+          type: ID
+      pipeline: [authorise, fetchAnimal]
+`)
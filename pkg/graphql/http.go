@@ -0,0 +1,119 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var reHTTPMethods = regexp.MustCompile(`^(GET|POST|PUT|PATCH|DELETE)$`)
+
+// defaultHTTPMethods gives the method used by each action where the resolver
+// doesn't declare one
+var defaultHTTPMethods = map[string]string{
+	ActionGet:    "GET",
+	ActionInsert: "POST",
+	ActionUpdate: "PUT",
+	ActionDelete: "DELETE",
+}
+
+type (
+	// HTTPRequest describes the request a resolver makes to an http source
+	//
+	//   http:
+	//     method: <GET|POST|PUT|PATCH|DELETE> # optional
+	//     path: /animals/$ctx.args.id
+	//     query: # optional
+	//       <parameter>: <argument_name>
+	//     headers: # optional
+	//       <header>: <value>
+	//     body: <argument_name> # optional
+	//
+	HTTPRequest struct {
+		// (Optional) Defaults to GET, POST, PUT or DELETE for the get, insert,
+		// update and delete actions respectively
+		Method string `yaml:"method"`
+
+		// Path of the resource, which may refer to the arguments of the field,
+		// e.g. /animals/$ctx.args.id
+		Path string `yaml:"path"`
+
+		// Query parameters mapped to the names of the arguments holding their
+		// values. Parameters are omitted where the argument is null.
+		Query map[string]string `yaml:"query"`
+
+		// Headers to send. Values may refer to the context, e.g.
+		// $ctx.request.headers.authorization
+		Headers map[string]string `yaml:"headers"`
+
+		// Name of the argument sent as the JSON body of the request. Defaults
+		// to `input` for the insert and update actions
+		Body string `yaml:"body"`
+	}
+
+	unmarshalHTTPRequest HTTPRequest
+)
+
+// UnmarshalYAML satisfies the custom unmarshaler interface for go-yaml. It is
+// called automatically by the YAML unmarshal to set and check defaults.
+func (h *HTTPRequest) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var u unmarshalHTTPRequest
+	if err := unmarshal(&u); err != nil {
+		return err
+	}
+
+	*h = HTTPRequest(u)
+
+	if h.Method != "" && !reHTTPMethods.MatchString(h.Method) {
+		return fmt.Errorf("http method '%s' must be one of GET, POST, PUT, PATCH or DELETE", h.Method)
+	}
+	if !strings.HasPrefix(h.Path, "/") {
+		return fmt.Errorf("http path '%s' must begin with /", h.Path)
+	}
+	return nil
+}
+
+// forAction returns a copy of the request with the defaults for the action
+// applied
+func (h *HTTPRequest) forAction(action string) *HTTPRequest {
+	c := *h
+	if c.Method == "" {
+		c.Method = defaultHTTPMethods[action]
+	}
+	if c.Body == "" && (action == ActionInsert || action == ActionUpdate) {
+		c.Body = "input"
+	}
+	return &c
+}
+
+// QueryJSONMap converts the query parameters into a JSON formatted map
+func (h *HTTPRequest) QueryJSONMap() string {
+	return jsonMap(h.Query)
+}
+
+// HeadersJSONMap converts the headers into a JSON formatted map. A JSON
+// content type is added where the request has a body.
+func (h *HTTPRequest) HeadersJSONMap() string {
+	headers := map[string]string{}
+	if h.Body != "" {
+		headers["Content-Type"] = "application/json"
+	}
+	for k, v := range h.Headers {
+		headers[k] = v
+	}
+	return jsonMap(headers)
+}
+
+// jsonMap formats the map as JSON, with keys in sorted order
+func jsonMap(m map[string]string) string {
+	if len(m) == 0 {
+		return "{}"
+	}
+	b := bytes.Buffer{}
+	e := json.NewEncoder(&b)
+	e.SetEscapeHTML(false)
+	_ = e.Encode(m)
+	return strings.TrimSpace(b.String())
+}
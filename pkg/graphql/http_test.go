@@ -0,0 +1,125 @@
+package graphql_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ONSdigital/aws-appsync-generator/pkg/graphql"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestUnmarshalHTTPRequest(t *testing.T) {
+	for _, c := range []struct {
+		scenario string
+		yaml     []byte
+		err      error
+	}{
+		{
+			"Path only",
+			[]byte("path: /animals/$ctx.args.id"),
+			nil,
+		},
+		{
+			"Bad method",
+			[]byte("method: FETCH\npath: /animals"),
+			errors.New("http method 'FETCH' must be one of GET, POST, PUT, PATCH or DELETE"),
+		},
+		{
+			"Relative path",
+			[]byte("path: animals"),
+			errors.New("http path 'animals' must begin with /"),
+		},
+	} {
+		var h graphql.HTTPRequest
+		err := yaml.Unmarshal(c.yaml, &h)
+		switch c.err {
+		case nil:
+			assert.NoError(t, err, c.scenario)
+		default:
+			assert.EqualError(t, err, c.err.Error(), c.scenario)
+		}
+	}
+}
+
+func TestGenerateHTTP(t *testing.T) {
+	files, err := mustCompileSchema(t, httpManifest).Generate()
+	if err != nil {
+		t.Fatalf("error generating schema '%v', expected nil", err)
+	}
+
+	source := string(files["_datasource_http_animals.tf"])
+	assert.Contains(t, source, `type				= "HTTP"`)
+	assert.Contains(t, source, `endpoint	= "https://api.example.com"`)
+	assert.Contains(t, source, `signing_service_name	= "execute-api"`)
+
+	get := string(files["_query_getanimal.tf"])
+	assert.Contains(t, get, `"method": "GET"`)
+	assert.Contains(t, get, `"resourcePath": "/animals/$ctx.args.id"`)
+	assert.Contains(t, get, `#set( $params = {"species":"species"} )`)
+	assert.Contains(t, get, `#set( $headers = {"x-api-version":"2"} )`)
+	assert.NotContains(t, get, `"body": $util.toJson`)
+	assert.Contains(t, get, `#if( $ctx.result.statusCode < 200 || $ctx.result.statusCode >= 300 )`)
+
+	create := string(files["_mutation_createanimal.tf"])
+	assert.Contains(t, create, `"method": "POST"`)
+	assert.Contains(t, create, `#set( $headers = {"Content-Type":"application/json"} )`)
+	assert.Contains(t, create, `"body": $util.toJson($ctx.args.input)`)
+
+	update := string(files["_mutation_updateanimal.tf"])
+	assert.Contains(t, update, `"method": "PATCH"`)
+}
+
+var httpManifest = []byte(`
+sources:
+  default:
+    name: animals
+    http:
+      endpoint: https://api.example.com
+      iam:
+        signing_region: eu-west-2
+        signing_service: execute-api
+        resources:
+          - arn:aws:execute-api:eu-west-2:123456789012:abc123/*
+
+objects:
+  - name: Animal
+    fields:
+      - name: id
+        type: ID!
+      - name: species
+
+queries:
+  - name: getAnimal
+    resolver:
+      action: get
+      type: Animal
+      keyFields:
+        - name: id
+          type: ID
+        - name: species
+      http:
+        path: /animals/$ctx.args.id
+        query:
+          species: species
+        headers:
+          x-api-version: "2"
+
+mutations:
+  - name: createAnimal
+    resolver:
+      action: insert
+      type: Animal
+      http:
+        path: /animals
+  - name: updateAnimal
+    resolver:
+      action: update
+      type: Animal
+      keyFields:
+        - name: id
+          type: ID
+      http:
+        method: PATCH
+        path: /animals/$ctx.args.input.id
+`)
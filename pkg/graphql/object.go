@@ -15,6 +15,13 @@ type (
 	Object struct {
 		Name   string   `yaml:"name"`
 		Fields []*Field `yaml:"fields"`
+
+		// (Optional) Authorization types allowed to access the object
+		Auth AuthList `yaml:"auth"`
+
+		// (Optional) If true, the object has a version, incremented by every
+		// update. Updates and deletes must supply the version they expect
+		Versioned bool `yaml:"versioned"`
 	}
 
 	// FilterObject is generated from a base object and used to filter queries
@@ -30,6 +37,53 @@ type (
 	InputObjectList []*InputObject
 )
 
+// versionField is the field added to versioned objects
+const versionField = "version"
+
+// expectedVersionArg is the argument of deletes of versioned objects giving
+// the version the client expects to delete
+const expectedVersionArg = "expectedVersion"
+
+// addVersionField adds the version field to a versioned object
+func (o *Object) addVersionField() error {
+	if !o.Versioned {
+		return nil
+	}
+	if o.hasField(versionField) {
+		return fmt.Errorf("versioned object '%s' cannot declare a field named '%s'", o.Name, versionField)
+	}
+	o.Fields = append(o.Fields, &Field{
+		Name: versionField,
+		Type: &FieldType{Name: "Int", NonNullable: true},
+	})
+	return nil
+}
+
+// ttlField returns the field holding the expiry time of the object, or nil
+// if it doesn't expire
+func (o *Object) ttlField() *Field {
+	for _, f := range o.Fields {
+		if f.TTL != nil {
+			return f
+		}
+	}
+	return nil
+}
+
+func (o *Object) hasField(name string) bool {
+	return o.field(name) != nil
+}
+
+// field returns the field of the object with the name, or nil
+func (o *Object) field(name string) *Field {
+	for _, f := range o.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
 // NewFilterFromObject creates a new filter object input type from an
 // existing Object definition.
 // Fields that can't be (currently) filtered (custom types and embedded objects)
@@ -73,7 +127,13 @@ func (s *Schema) AddFilterFromObject(o *Object) {
 	if s.FilterObjects == nil {
 		s.FilterObjects = make(FilterObjectList, 0, 1)
 	}
-	s.FilterObjects = append(s.FilterObjects, NewFilterFromObject(o))
+	fo := NewFilterFromObject(o)
+	for _, existing := range s.FilterObjects {
+		if existing.Name == fo.Name {
+			return
+		}
+	}
+	s.FilterObjects = append(s.FilterObjects, fo)
 }
 
 // NewInputFromObject creates a new input object type from an
@@ -103,6 +163,18 @@ func NewInputFromObject(o *Object, action string) (*InputObject, error) {
 			continue
 		}
 
+		// The version of a versioned object starts at 1 when it's created,
+		// and must be given by updates
+		if o.Versioned && f.Name == versionField {
+			if action == ActionUpdate {
+				io.Fields = append(io.Fields, &Field{
+					Name: f.Name,
+					Type: &FieldType{Name: "Int", NonNullable: true},
+				})
+			}
+			continue
+		}
+
 		fieldTypeName := f.Type.Name
 
 		// Allow overriding of types to be different for the input object
@@ -110,11 +182,12 @@ func NewInputFromObject(o *Object, action string) (*InputObject, error) {
 			fieldTypeName = f.InputType.Name
 		}
 
-		// If this is an insert (create) object, then we omit any "id" field
-		// with a type "ID!" as it should be autogenerated
-		// if action == ActionInsert && f.Name == "id" && f.Type.Name == "ID" && f.Type.NonNullable {
-		// 	continue
-		// }
+		// Generated values are never given when creating an object. Generated
+		// identifiers are given by updates to find the object to update, but
+		// timestamps never are
+		if f.Generated != "" && (action == ActionInsert || !f.generatesID()) {
+			continue
+		}
 
 		// TODO Deal with enum fields that should map as strings
 
@@ -141,6 +214,34 @@ func (s *Schema) AddInputFromObject(o *Object, action string) error {
 	if s.InputObjects == nil {
 		s.InputObjects = make(InputObjectList, 0, 1)
 	}
+	for _, existing := range s.InputObjects {
+		if existing.Name == io.Name {
+			return nil
+		}
+	}
 	s.InputObjects = append(s.InputObjects, io)
 	return nil
 }
+
+// addKeyInput adds an input object of the key fields of the resolver, taken
+// as a list by the batch actions
+func (s *Schema) addKeyInput(r *Resolver) {
+	io := &InputObject{
+		Name: fmt.Sprintf("%sKeyInput", r.Type.Name),
+	}
+	for _, existing := range s.InputObjects {
+		if existing.Name == io.Name {
+			return
+		}
+	}
+	for _, k := range r.KeyFields {
+		io.Fields = append(io.Fields, &Field{
+			Name: k.Name,
+			Type: &FieldType{
+				Name:        k.Type.Name,
+				NonNullable: true,
+			},
+		})
+	}
+	s.InputObjects = append(s.InputObjects, io)
+}
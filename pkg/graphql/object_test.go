@@ -64,3 +64,15 @@ func TestNewFilterFromObject(t *testing.T) {
 	generated := graphql.NewFilterFromObject(in)
 	assert.Equal(t, expected, generated)
 }
+
+func TestVersionedObjectCannotDeclareVersion(t *testing.T) {
+	_, err := graphql.NewSchemaFromManifest([]byte(`
+objects:
+  - name: Animal
+    versioned: true
+    fields:
+      - name: version
+        type: String
+`))
+	assert.EqualError(t, err, "versioned object 'Animal' cannot declare a field named 'version'")
+}
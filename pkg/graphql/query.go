@@ -9,6 +9,7 @@ type (
 	//    resolver:
 	//       action: <get|list|insert|update>
 	//       source: <source_name> # optional
+	//     auth: [<auth_type>, ...] # optional
 	//   ...
 	//   }
 	//
@@ -16,6 +17,7 @@ type (
 		Name     string     `yaml:"name"`
 		Type     *FieldType `yaml:"type"`
 		Resolver *Resolver  `yaml:"resolver"`
+		Auth     AuthList   `yaml:"auth"`
 	}
 
 	// Mutation is a mutation query type
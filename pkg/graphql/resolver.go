@@ -9,11 +9,35 @@ import (
 
 // Constants for resolver actions
 const (
-	ActionGet    = "get"
-	ActionList   = "list"
-	ActionUpdate = "update"
-	ActionDelete = "delete"
-	ActionInsert = "insert"
+	ActionGet      = "get"
+	ActionGetItems = "get-items"
+	ActionList     = "list"
+	ActionUpdate   = "update"
+	ActionDelete   = "delete"
+	ActionInsert   = "insert"
+
+	// Dynamo sources
+	ActionQuery       = "query"
+	ActionBatchGet    = "batch-get"
+	ActionBatchInsert = "batch-insert"
+	ActionBatchDelete = "batch-delete"
+	ActionTransact    = "transact"
+
+	// Opensearch sources
+	ActionSearch = "search"
+
+	// Eventbridge sources
+	ActionPublish = "publish"
+
+	// None sources
+	ActionLocal = "local"
+
+	// Lambda sources
+	ActionInvoke      = "invoke"
+	ActionBatchInvoke = "batch-invoke"
+
+	// Set automatically for subscriptions with enhanced filtering
+	ActionSubscribe = "subscribe"
 )
 
 type (
@@ -41,15 +65,66 @@ type (
 		// If false, sort in descending order.
 		SortAscending *bool `yaml:"sortAscending"`
 
+		// Name of the secondary index of a dynamo source to query, for the
+		// get-items and list actions. The key fields are those of the index
+		Index string `yaml:"index"`
+
+		// The maximum number of source items sent to the function in one
+		// batch-invoke. Default 10
+		MaxBatchSize int `yaml:"maxBatchSize"`
+
+		// The writes made atomically by a transact resolver, in order
+		Transact []*TransactStep `yaml:"transact"`
+
+		// The request made by resolvers using an http source
+		HTTP *HTTPRequest `yaml:"http"`
+
+		// Names of the functions to run, in order, as a pipeline resolver.
+		// Pipeline resolvers don't use a data source; the action, where
+		// declared, only shapes the arguments of the field in the schema
+		Pipeline []string `yaml:"pipeline"`
+
+		// Mapping templates run before and after the pipeline functions.
+		// Default to passing the arguments in and returning the result of
+		// the last function
+		Before string `yaml:"before"`
+		After  string `yaml:"after"`
+
 		// The below are set automatically as the schema is parsed. They should
 		// not be included in the manifest YAML.
 		DataSource *Source // Key to a datasource defined in the manifest
+		Object     *Object // Object definition of the returned type
+		API        *API    // API the resolver is attached to
 		ArgsSource string  // $ctx.{{ArgSource}}.get() - "args" or "source"
 		Parent     string  // Parent field
 		FieldName  string  // Field name attached to
 	}
 )
 
+const (
+	defaultPipelineBefore = "{}"
+	defaultPipelineAfter  = "$util.toJson($ctx.prev.result)"
+	defaultMaxBatchSize   = 10
+)
+
+// IsPipeline reports whether the resolver is a pipeline of functions rather
+// than a unit resolver using a single data source
+func (r *Resolver) IsPipeline() bool {
+	return len(r.Pipeline) > 0
+}
+
+// BatchSize returns the maximum batch size of a batch-invoke resolver, or
+// zero where the resolver doesn't batch
+func (r *Resolver) BatchSize() int {
+	switch {
+	case r.Action != ActionBatchInvoke:
+		return 0
+	case r.MaxBatchSize > 0:
+		return r.MaxBatchSize
+	}
+	return defaultMaxBatchSize
+}
+
 // KeyFieldJSONMap converts the `KeyFields` into a JSON formatted map
 func (r *Resolver) KeyFieldJSONMap() string {
 	fl := make([]string, len(r.KeyFields))
@@ -68,6 +143,38 @@ func (r *Resolver) KeyFieldJSONList() string {
 	return "[" + strings.Join(fl, ",") + "]"
 }
 
+// KeyFieldArgJSONMap converts the `KeyFields` into a JSON formatted map of
+// key field name to the name of the argument (or parent field, where the
+// resolver is nested) that holds the value of the key
+func (r *Resolver) KeyFieldArgJSONMap() string {
+	fl := make([]string, len(r.KeyFields))
+	for i, f := range r.KeyFields {
+		arg := f.Name
+		if r.ArgsSource == "source" && f.Parent != "" {
+			arg = f.Parent
+		}
+		fl[i] = fmt.Sprintf(`"%s":"%s"`, f.Name, arg)
+	}
+	return "{" + strings.Join(fl, ",") + "}"
+}
+
+// ColumnJSONList converts the fields of the returned object that map directly
+// to stored values (i.e. those without their own resolver) into a JSON
+// formatted list of names
+func (r *Resolver) ColumnJSONList() string {
+	if r.Object == nil {
+		return "[]"
+	}
+	fl := []string{}
+	for _, f := range r.Object.Fields {
+		if f.Resolver != nil {
+			continue
+		}
+		fl = append(fl, fmt.Sprintf(`"%s"`, f.Name))
+	}
+	return "[" + strings.Join(fl, ",") + "]"
+}
+
 // KeyFieldArgsString returns the keyfield names and types in a string format
 // suitable to be used as the arguments list in a resolver definition in
 // the schemea
@@ -76,10 +183,48 @@ func (r *Resolver) KeyFieldArgsString() string {
 	switch r.Action {
 	case ActionList:
 		return fmt.Sprintf("(filter: %sFilter, limit: Int, nextToken: String)", r.Type.Name)
+	case ActionSearch:
+		return fmt.Sprintf("(filter: %sFilter, sort: [SearchSortInput], from: Int, size: Int, nextToken: String)", r.Type.Name)
+	case ActionBatchGet, ActionBatchDelete:
+		return fmt.Sprintf("(keys: [%sKeyInput!]!)", r.Type.Name)
+	case ActionBatchInsert:
+		return fmt.Sprintf("(input: [Create%sInput!]!)", r.Type.Name)
+	case ActionQuery:
+		// The hash key is required and the sort key, if any, is a condition
+		fl := []string{}
+		if len(r.KeyFields) > 0 {
+			fl = append(fl, fmt.Sprintf("%s: %s!", r.KeyFields[0].Name, r.KeyFields[0].Type.Name))
+		}
+		if c := r.SortKeyCondition(); c != "" {
+			fl = append(fl, fmt.Sprintf("%s: %s", r.KeyFields[1].Name, c))
+		}
+		fl = append(fl, "limit: Int", "nextToken: String", "sortAscending: Boolean")
+		return "(" + strings.Join(fl, ", ") + ")"
 	case ActionInsert:
 		return fmt.Sprintf("(input: Create%sInput)", r.Type.Name)
 	case ActionUpdate:
 		return fmt.Sprintf("(input: Update%sInput)", r.Type.Name)
+	case ActionPublish, ActionTransact:
+		return fmt.Sprintf("(input: Create%sInput)", r.Type.Name)
+	case ActionLocal:
+		// Local resolvers echo their key fields where they declare them
+		if len(r.KeyFields) == 0 {
+			return fmt.Sprintf("(input: Create%sInput)", r.Type.Name)
+		}
+	case ActionDelete:
+		// All keys are required to identify the record to be deleted
+		if l := len(r.KeyFields); l > 0 {
+			fl := make([]string, l)
+			for i, f := range r.KeyFields {
+				fl[i] = fmt.Sprintf("%s: %s!", f.Name, f.Type.Name)
+			}
+			// As is the version of a versioned record
+			if r.isVersioned() {
+				fl = append(fl, expectedVersionArg+": Int!")
+			}
+			return "(" + strings.Join(fl, ", ") + ")"
+		}
+		return ""
 	}
 
 	if l := len(r.KeyFields); l > 0 {
@@ -97,49 +242,250 @@ func (r *Resolver) KeyFieldArgsString() string {
 	return ""
 }
 
+// IsBatch reports whether the resolver reads or writes many dynamo items
+// in one request
+func (r *Resolver) IsBatch() bool {
+	switch r.Action {
+	case ActionBatchGet, ActionBatchInsert, ActionBatchDelete:
+		return true
+	}
+	return false
+}
+
+// SortKeyCondition returns the name of the input type of the sort key
+// condition taken by query actions, or "" where there is no sort key
+func (r *Resolver) SortKeyCondition() string {
+	if r.Action != ActionQuery || len(r.KeyFields) < 2 {
+		return ""
+	}
+	return fmt.Sprintf("Table%sSortKeyCondition", r.KeyFields[1].Type.Name)
+}
+
+// isVersioned reports whether the resolver reads or writes a versioned object
+// of a dynamo source
+func (r *Resolver) isVersioned() bool {
+	return r.Object != nil && r.Object.Versioned && r.DataSource != nil && r.DataSource.Type == "dynamo"
+}
+
+// SyncConfig returns the conflict detection of the resolver, where it writes
+// a versioned object to a dynamo source with conflict detection, or nil
+func (r *Resolver) SyncConfig() *DynamoConflict {
+	if !r.isVersioned() {
+		return nil
+	}
+	switch r.Action {
+	case ActionInsert, ActionUpdate, ActionDelete:
+		return r.DataSource.Dynamo.Conflict
+	}
+	return nil
+}
+
+// generatedFields returns the fields of the returned object whose values are
+// generated by the resolver. Inserts, batch inserts and publishes generate
+// every value, updates only the time of the update. Transactions generate the
+// values of the object of each step
+func (r *Resolver) generatedFields() []*Field {
+	fl := []*Field{}
+	if r.Action == ActionTransact {
+		for _, t := range r.Transact {
+			fl = append(fl, t.Generated()...)
+		}
+		return fl
+	}
+	if r.Object == nil {
+		return nil
+	}
+	for _, f := range r.Object.Fields {
+		if f.Generated == "" {
+			continue
+		}
+		if r.Action == ActionInsert || r.Action == ActionBatchInsert || r.Action == ActionPublish || (r.Action == ActionUpdate && f.Generated == GeneratedUpdatedAt) {
+			fl = append(fl, f)
+		}
+	}
+	return fl
+}
+
+// generatedKey returns the hash key of the resolver's dynamo table where the
+// resolver generates it for the object it creates, or an empty string
+func (r *Resolver) generatedKey() string {
+	if r.DataSource == nil || r.DataSource.Type != "dynamo" {
+		return ""
+	}
+	for _, f := range r.generatedFields() {
+		if f.generatesID() && f.Name == r.DataSource.Dynamo.HashKey.Name {
+			return f.Name
+		}
+	}
+	return ""
+}
+
+// inputAction returns the action whose input object the resolver takes as
+// its argument, or "" where it takes none
+func (r *Resolver) inputAction() string {
+	switch {
+	case r.Action == ActionInsert, r.Action == ActionPublish, r.Action == ActionBatchInsert, r.Action == ActionTransact:
+		return ActionInsert
+	case r.Action == ActionUpdate:
+		return ActionUpdate
+	case r.Action == ActionLocal && len(r.KeyFields) == 0:
+		return ActionInsert
+	}
+	return ""
+}
+
 // GenerateBytes renders the resolver ready to be written to an output stream
 func (r *Resolver) GenerateBytes() ([]byte, error) {
 	generated := bytes.Buffer{}
 
+	request, response, err := r.mappingTemplates()
+	if err != nil {
+		return nil, err
+	}
+
 	t, err := template.New(r.Action).Funcs(funcMap).Parse(resolverTemplate)
 	if err != nil {
 		return nil, err
 	}
 
+	d := struct {
+		*Resolver
+		Request  string
+		Response string
+	}{r, request, response}
+
+	if err := t.Execute(&generated, d); err != nil {
+		return nil, err
+	}
+	return generated.Bytes(), nil
+}
+
+// mappingTemplates renders the request and response mapping templates for
+// the resolver
+func (r *Resolver) mappingTemplates() (string, string, error) {
+	if (r.Action == ActionList || r.Action == ActionQuery || r.Action == ActionSearch || r.IsBatch()) && !r.Type.IsList {
+		return "", "", fmt.Errorf("mismatched resolver - when Action is %s, Type must be a list type: %s", r.Action, r.FieldName)
+	}
+
+	if r.IsPipeline() {
+		before, after := strings.TrimSpace(r.Before), strings.TrimSpace(r.After)
+		if before == "" {
+			before = defaultPipelineBefore
+		}
+		if after == "" {
+			after = defaultPipelineAfter
+		}
+		return before, after, nil
+	}
+
 	nested := ""
 	if r.ArgsSource == "source" {
 		nested = "-nested"
 	}
 
-	t, err = t.ParseFiles(
-		"templates/resolvers/"+r.DataSource.Type+"/request/"+r.Action+nested+".tmpl",
-		"templates/resolvers/"+r.DataSource.Type+"/response/"+r.Action+nested+".tmpl",
-	)
-	if err != nil {
-		return nil, err
+	t := template.New(r.Action).Funcs(funcMap)
+	for _, kind := range []string{"request", "response"} {
+		name := r.DataSource.Type + "/" + kind + "/" + r.Action + nested + ".tmpl"
+
+		// Http actions differ only in the defaults of their request, so
+		// share a single pair of templates
+		if r.DataSource.Type == "http" {
+			name = "http/" + kind + ".tmpl"
+		}
+		content, err := loadResolverTemplate(name)
+		if err != nil && nested != "" {
+			// Not every action needs a nested variant
+			name = r.DataSource.Type + "/" + kind + "/" + r.Action + ".tmpl"
+			content, err = loadResolverTemplate(name)
+		}
+		if err != nil {
+			return "", "", err
+		}
+		if _, err := t.New(name).Parse(content); err != nil {
+			return "", "", err
+		}
 	}
 
 	type ResolverData struct {
-		KeyFieldJSONMap  string
-		KeyFieldJSONList string
-		SortAscending    bool
-		ArgsSource       string
-		HashKey          string
-		SortKey          string
-		ParentKey        string
-		Parent           string
-		FieldName        string
-		DataSource       *Source
+		KeyFieldJSONMap    string
+		KeyFieldJSONList   string
+		KeyFieldArgJSONMap string
+		ColumnJSONList     string
+		SortAscending      bool
+		ArgsSource         string
+		HashKey            string
+		SortKey            string
+		ParentKey          string
+		Parent             string
+		FieldName          string
+		Table              string
+		Index              string
+		DetailType         string
+		DataSource         *Source
+		Transact           []*TransactStep
+		Versioned          bool
+		Synced             bool
+		Generated          []*Field
+		GeneratedKey       string
+		TTLField           *Field
+		TTLAttribute       string
+		HTTP               *HTTPRequest
 	}
 
 	d := ResolverData{
-		KeyFieldJSONMap:  r.KeyFieldJSONMap(),
-		KeyFieldJSONList: r.KeyFieldJSONList(),
-		SortAscending:    r.SortAscending == nil || *r.SortAscending,
-		ArgsSource:       r.ArgsSource,
-		Parent:           r.Parent,
-		FieldName:        r.FieldName,
-		DataSource:       r.DataSource,
+		KeyFieldJSONMap:    r.KeyFieldJSONMap(),
+		KeyFieldJSONList:   r.KeyFieldJSONList(),
+		KeyFieldArgJSONMap: r.KeyFieldArgJSONMap(),
+		ColumnJSONList:     r.ColumnJSONList(),
+		SortAscending:      r.SortAscending == nil || *r.SortAscending,
+		ArgsSource:         r.ArgsSource,
+		Parent:             r.Parent,
+		FieldName:          r.FieldName,
+		DataSource:         r.DataSource,
+		Transact:           r.Transact,
+		Versioned:          r.isVersioned(),
+		Synced:             r.SyncConfig() != nil,
+		Generated:          r.generatedFields(),
+		GeneratedKey:       r.generatedKey(),
+	}
+
+	if r.DataSource.Type == "sql" {
+		// Without a key the where clause is empty, so writes would change
+		// every row of the table
+		if (r.Action == ActionInsert || r.Action == ActionUpdate || r.Action == ActionDelete) && len(r.KeyFields) == 0 {
+			return "", "", fmt.Errorf("resolver '%s_%s' must declare keyFields to %s rows of sql source '%s'", r.Parent, r.FieldName, r.Action, r.DataSource.Name)
+		}
+		d.Table = r.DataSource.SQL.Table
+	}
+
+	if r.DataSource.Type == "opensearch" {
+		d.Index = r.DataSource.OpenSearch.Index
+	}
+
+	if r.DataSource.Type == "eventbridge" {
+		d.DetailType = r.DataSource.EventBridge.DetailType
+		if d.DetailType == "" {
+			d.DetailType = r.FieldName
+		}
+	}
+
+	if r.DataSource.Type == "http" {
+		if r.HTTP == nil {
+			return "", "", fmt.Errorf("resolver '%s_%s' uses http source '%s' but does not declare an http request", r.Parent, r.FieldName, r.DataSource.Name)
+		}
+		d.HTTP = r.HTTP.forAction(r.Action)
+	}
+
+	if r.DataSource.Type == "dynamo" {
+		d.Index = r.Index
+	}
+
+	// Writes store the expiry time of the object as the ttl of the item
+	if r.DataSource.Type == "dynamo" && r.DataSource.Dynamo.TTL != nil && r.Object != nil && (r.Action == ActionInsert || r.Action == ActionUpdate) {
+		if f := r.Object.ttlField(); f != nil {
+			d.TTLField = f
+			d.TTLAttribute = r.DataSource.Dynamo.TTL.Attribute
+		}
 	}
 
 	if r.DataSource.Type == "dynamo" && len(r.KeyFields) > 0 {
@@ -152,14 +498,28 @@ func (r *Resolver) GenerateBytes() ([]byte, error) {
 		}
 	}
 
-	if r.Action == ActionList && !r.Type.IsList {
-		return nil, fmt.Errorf("mismatched resolver - when Action is list, Type must be a list type: %s", r.FieldName)
+	// Unkeyed lists query by the keys of the table, or index, where the
+	// filter supplies them
+	if r.DataSource.Type == "dynamo" && len(r.KeyFields) == 0 && r.Action == ActionList {
+		hashKey, sortKey := r.DataSource.Dynamo.HashKey, r.DataSource.Dynamo.SortKey
+		if idx := r.DataSource.Dynamo.index(r.Index); idx != nil {
+			hashKey, sortKey = idx.HashKey, idx.SortKey
+		}
+		d.HashKey = hashKey.Name
+		if sortKey != nil {
+			d.SortKey = sortKey.Name
+		}
 	}
 
-	if err := t.Execute(&generated, d); err != nil {
-		return nil, err
+	request := bytes.Buffer{}
+	if err := t.ExecuteTemplate(&request, "request", d); err != nil {
+		return "", "", err
 	}
-	return generated.Bytes(), nil
+	response := bytes.Buffer{}
+	if err := t.ExecuteTemplate(&response, "response", d); err != nil {
+		return "", "", err
+	}
+	return request.String(), response.String(), nil
 }
 
 // OutputName returns the file name to be written for the resolver
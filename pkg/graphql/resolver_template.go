@@ -1,18 +1,46 @@
 package graphql
 
 var resolverTemplate = `
-## !NOTE: This file is auto-generated DO NOT EDIT
-## Generated at {{now}}
 resource "aws_appsync_resolver" "{{.Parent}}_{{.FieldName}}" {
-	api_id            = aws_appsync_graphql_api.record.id
+	api_id            = aws_appsync_graphql_api.{{.API.Name}}.id
 	type              = "{{.Parent}}"
 	field             = "{{.FieldName}}"
+	{{- if .IsPipeline }}
+	kind              = "PIPELINE"
+	{{- else }}
 	data_source       = aws_appsync_datasource.{{ .DataSource.Name }}.name
+	{{- end }}
+	{{- if .BatchSize }}
+	max_batch_size    = {{ .BatchSize }}
+	{{- end }}
 	request_template  = <<EOF
-{{template "request" .}}
+{{ .Request }}
 EOF
 	response_template = <<EOF
-{{template "response" .}}
+{{ .Response }}
 EOF
+	{{- with .SyncConfig }}
+
+	sync_config {
+		conflict_detection = "VERSION"
+		conflict_handler   = "{{ .Handler }}"
+		{{- if .LambdaARN }}
+
+		lambda_conflict_handler_config {
+			lambda_conflict_handler_arn = "{{ .LambdaARN }}"
+		}
+		{{- end }}
+	}
+	{{- end }}
+	{{- if .IsPipeline }}
+
+	pipeline_config {
+		functions = [
+			{{- range .Pipeline }}
+			aws_appsync_function.{{ . }}.function_id,
+			{{- end }}
+		]
+	}
+	{{- end }}
 }
 `
@@ -0,0 +1,859 @@
+package graphql_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ONSdigital/aws-appsync-generator/pkg/graphql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyFieldArgsString(t *testing.T) {
+	keyFields := []*graphql.Field{
+		{Name: "id", Type: &graphql.FieldType{Name: "ID"}},
+		{Name: "version", Type: &graphql.FieldType{Name: "Int"}},
+	}
+
+	for _, c := range []struct {
+		scenario string
+		resolver *graphql.Resolver
+		expected string
+	}{
+		{
+			"Get with key fields",
+			&graphql.Resolver{Action: graphql.ActionGet, Type: &graphql.FieldType{Name: "Cat"}, KeyFields: keyFields[:1]},
+			"(id:ID)",
+		},
+		{
+			"List",
+			&graphql.Resolver{Action: graphql.ActionList, Type: &graphql.FieldType{Name: "Cat", IsList: true}},
+			"(filter: CatFilter, limit: Int, nextToken: String)",
+		},
+		{
+			"Insert",
+			&graphql.Resolver{Action: graphql.ActionInsert, Type: &graphql.FieldType{Name: "Cat"}},
+			"(input: CreateCatInput)",
+		},
+		{
+			"Update",
+			&graphql.Resolver{Action: graphql.ActionUpdate, Type: &graphql.FieldType{Name: "Cat"}},
+			"(input: UpdateCatInput)",
+		},
+		{
+			"Delete with single key",
+			&graphql.Resolver{Action: graphql.ActionDelete, Type: &graphql.FieldType{Name: "Cat"}, KeyFields: keyFields[:1]},
+			"(id: ID!)",
+		},
+		{
+			"Delete with composite key",
+			&graphql.Resolver{Action: graphql.ActionDelete, Type: &graphql.FieldType{Name: "Cat"}, KeyFields: keyFields},
+			"(id: ID!, version: Int!)",
+		},
+		{
+			"Query by hash key",
+			&graphql.Resolver{Action: graphql.ActionQuery, Type: &graphql.FieldType{Name: "Cat", IsList: true}, KeyFields: keyFields[:1]},
+			"(id: ID!, limit: Int, nextToken: String, sortAscending: Boolean)",
+		},
+		{
+			"Query with sort key condition",
+			&graphql.Resolver{Action: graphql.ActionQuery, Type: &graphql.FieldType{Name: "Cat", IsList: true}, KeyFields: keyFields},
+			"(id: ID!, version: TableIntSortKeyCondition, limit: Int, nextToken: String, sortAscending: Boolean)",
+		},
+		{
+			"Batch get",
+			&graphql.Resolver{Action: graphql.ActionBatchGet, Type: &graphql.FieldType{Name: "Cat", IsList: true}, KeyFields: keyFields},
+			"(keys: [CatKeyInput!]!)",
+		},
+		{
+			"Batch insert",
+			&graphql.Resolver{Action: graphql.ActionBatchInsert, Type: &graphql.FieldType{Name: "Cat", IsList: true}},
+			"(input: [CreateCatInput!]!)",
+		},
+	} {
+		assert.Equal(t, c.expected, c.resolver.KeyFieldArgsString(), c.scenario)
+	}
+}
+
+// collapse joins runs of whitespace into single spaces, so templates compare
+// by their content rather than by their indentation
+func collapse(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// terraformResolverFile is the file generated for the resolver of a field,
+// given as "Type.field"
+func terraformResolverFile(field string) string {
+	return "_" + strings.ToLower(strings.Replace(field, ".", "_", 1)) + ".tf"
+}
+
+func TestGenerateResolvers(t *testing.T) {
+	for _, c := range []struct {
+		scenario   string
+		manifest   []byte
+		field      string
+		properties map[string]interface{}
+		expected   []string
+		unexpected []string
+		terraform  []string
+	}{
+		{
+			"Search",
+			searchManifest,
+			"Query.searchAnimals",
+			map[string]interface{}{"DataSourceName": "AnimalsSearchDataSource.Name"},
+			[]string{
+				`"path": "/animals/_search"`,
+				`$util.qr($must.add({"term": {"$field": $value}}))`,
+				`$util.qr($must.add({"range": {"$field": {"gte": $value[0], "lte": $value[1]}}}))`,
+				`$util.qr($must.add({"match": {"$field": $value}}))`,
+				`"total": $total`,
+			},
+			nil,
+			nil,
+		},
+		{
+			"Publish with the source detail type",
+			eventBridgeManifest,
+			"Mutation.animalFed",
+			map[string]interface{}{"DataSourceName": "ZooEventsDataSource.Name"},
+			[]string{
+				`"operation": "PutEvents"`,
+				`"source": "com.example.zoo"`,
+				`"detailType": "AnimalFed"`,
+				`"detail": $util.toJson($ctx.args.input)`,
+			},
+			nil,
+			nil,
+		},
+		{
+			"Publish with the field as detail type",
+			eventBridgeManifest,
+			"Mutation.animalNamed",
+			map[string]interface{}{"DataSourceName": "ZooNamedEventsDataSource.Name"},
+			[]string{`"detailType": "animalNamed"`},
+			nil,
+			nil,
+		},
+		{
+			"Local",
+			eventBridgeManifest,
+			"Mutation.animalMoved",
+			map[string]interface{}{"DataSourceName": "LocalDataSource.Name"},
+			[]string{
+				`"payload": $util.toJson($util.defaultIfNull($ctx.args.input, $ctx.args))`,
+				`$util.toJson($ctx.result)`,
+			},
+			nil,
+			nil,
+		},
+		{
+			"Batch get",
+			batchManifest,
+			"Query.getAnimals",
+			nil,
+			[]string{
+				`"operation": "BatchGetItem",`,
+				`#if( $keys.size() > 100 )`,
+				`"${Environment}-animals": {`,
+				`"items": $util.toJson($ctx.result.data.get("${Environment}-animals")),`,
+				`"unprocessedKeys": $util.toJson($util.defaultIfNull($ctx.result.unprocessedKeys.get("${Environment}-animals"), []))`,
+			},
+			nil,
+			[]string{`"${terraform.workspace}-animals": {`},
+		},
+		{
+			"Nested batch get",
+			batchManifest,
+			"Animal.keepers",
+			map[string]interface{}{"DataSourceName": "KeepersDataSource.Name"},
+			[]string{
+				`#foreach( $value in $util.defaultIfNull($ctx.source.keeperIds, []) )`,
+				`$util.qr($keys.add({"keeperId": $util.dynamodb.toDynamoDB($value)}))`,
+				`#if( $keys.size() > 100 )`,
+				`$util.toJson($ctx.result.data.get("${Environment}-keepers"))`,
+			},
+			nil,
+			nil,
+		},
+		{
+			"Batch insert",
+			batchManifest,
+			"Mutation.createKeepers",
+			nil,
+			[]string{
+				`"operation": "BatchPutItem",`,
+				`$util.error("A batch can write at most 25 items, not $items.size()", "ValidationError")`,
+				`#set( $key = {"keeperId": $item.get("keeperId")} )`,
+			},
+			[]string{`$util.qr($input.put("version", 1))`},
+			nil,
+		},
+		{
+			"Batch delete",
+			batchManifest,
+			"Mutation.deleteAnimals",
+			nil,
+			[]string{
+				`"operation": "BatchDeleteItem",`,
+				`$util.error("A batch can delete at most 25 items, not $keys.size()", "ValidationError")`,
+				`"${Environment}-animals": $util.toJson($keys)`,
+			},
+			nil,
+			nil,
+		},
+		{
+			"Versioned insert",
+			versionedManifest,
+			"Mutation.createAnimal",
+			nil,
+			[]string{`$util.qr($ctx.args.input.put("version", 1))`},
+			nil,
+			nil,
+		},
+		{
+			"Versioned update",
+			versionedManifest,
+			"Mutation.updateAnimal",
+			map[string]interface{}{"SyncConfig": nil},
+			[]string{
+				`#set( $expression = "$expression ADD #version :versionIncrement" )`,
+				`"expression" : "attribute_exists(#keyExists) AND #version = :expectedVersion",`,
+				`":expectedVersion" : $util.dynamodb.toDynamoDBJson($expectedVersion)`,
+				`"ConflictError"`,
+			},
+			nil,
+			nil,
+		},
+		{
+			"Versioned delete",
+			versionedManifest,
+			"Mutation.deleteAnimal",
+			nil,
+			[]string{
+				`"expression" : "attribute_exists(#keyExists) AND #version = :expectedVersion",`,
+				`"#keyExists" : "id",`,
+				`failed: record to delete does not exist", "NotFound")`,
+				`failed: record to delete is not at version $ctx.args.expectedVersion", "ConflictError")`,
+				`":expectedVersion" : $util.dynamodb.toDynamoDBJson($ctx.args.expectedVersion)`,
+			},
+			nil,
+			nil,
+		},
+		{
+			"Update detecting conflicts",
+			versionedManifest,
+			"Mutation.updateKeeper",
+			map[string]interface{}{"SyncConfig": map[string]interface{}{"ConflictDetection": "VERSION", "ConflictHandler": "AUTOMERGE"}},
+			[]string{
+				`"_version" : $util.toJson($expectedVersion),`,
+				`"expression" : "attribute_exists(#keyExists)",`,
+			},
+			nil,
+			[]string{`sync_config { conflict_detection = "VERSION" conflict_handler = "AUTOMERGE" }`},
+		},
+		{
+			"Delete detecting conflicts",
+			versionedManifest,
+			"Mutation.deleteKeeper",
+			nil,
+			[]string{`"_version" : $util.toJson($ctx.args.expectedVersion)`},
+			[]string{"ConflictError"},
+			nil,
+		},
+		{
+			"Get from a source detecting conflicts",
+			versionedManifest,
+			"Query.getKeeper",
+			map[string]interface{}{"SyncConfig": nil},
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"Insert with a generated hash key",
+			generatedManifest,
+			"Mutation.createAnimal",
+			nil,
+			[]string{
+				`#set( $now = $util.time.nowISO8601() )
+				$util.qr($ctx.args.input.put("id", $util.autoId()))
+				$util.qr($ctx.args.input.put("createdAt", $now))
+				$util.qr($ctx.args.input.put("updatedAt", $now))`,
+				`"expression" : "attribute_not_exists(#keyExists)",`,
+				`failed: a record with the generated id already exists", "ConflictError")`,
+			},
+			nil,
+			nil,
+		},
+		{
+			"Update with generated timestamps",
+			generatedManifest,
+			"Mutation.updateAnimal",
+			nil,
+			[]string{`$util.qr($ctx.args.input.put("updatedAt", $now))`},
+			[]string{"createdAt"},
+			nil,
+		},
+		{
+			"Sql insert with a generated key",
+			generatedManifest,
+			"Mutation.createKeeper",
+			nil,
+			[]string{
+				`$util.qr($ctx.args.input.put("id", $util.autoUlid()))`,
+				`"INSERT INTO keepers ($names) VALUES ($values)",`,
+			},
+			nil,
+			nil,
+		},
+		{
+			// Without a generated hash key, inserts may overwrite
+			"Insert with a generated non-key field",
+			generatedManifest,
+			"Mutation.createToy",
+			nil,
+			[]string{
+				`$util.qr($ctx.args.input.put("createdAt", $now))`,
+				`$util.qr($ctx.args.input.put("ref", $util.autoId()))`,
+			},
+			[]string{"ConflictError", "attribute_not_exists"},
+			nil,
+		},
+		{
+			"Insert with a default lifetime",
+			ttlManifest,
+			"Mutation.createSession",
+			nil,
+			[]string{
+				`#if( $util.isNull($ctx.args.input.expiresAt) )
+					#set( $expires = $util.time.nowEpochSeconds() + 3600 )
+					#set( $expiresMillis = $expires * 1000 )
+					$util.qr($ctx.args.input.put("expiresAt", $util.time.epochMilliSecondsToISO8601($expiresMillis)))
+				#end`,
+				`#set( $expires = $util.time.parseISO8601ToEpochMilliSeconds($ctx.args.input.expiresAt) / 1000 )
+				$util.qr($ctx.args.input.put("ttl", $expires))`,
+			},
+			nil,
+			nil,
+		},
+		{
+			// Updates only change the ttl when given a new expiry
+			"Update with a default lifetime",
+			ttlManifest,
+			"Mutation.updateSession",
+			nil,
+			[]string{`$util.qr($ctx.args.input.put("ttl", $expires))`},
+			[]string{"nowEpochSeconds"},
+			nil,
+		},
+		{
+			// Timestamps are already epoch seconds, and without a default
+			// lifetime tokens only expire when told to
+			"Insert with a timestamp expiry",
+			ttlManifest,
+			"Mutation.createToken",
+			nil,
+			[]string{
+				`#set( $expires = $ctx.args.input.expiresAt )
+				$util.qr($ctx.args.input.put("expiresAt", $expires))`,
+			},
+			[]string{"nowEpochSeconds"},
+			nil,
+		},
+		{
+			"Update",
+			updateManifest,
+			"Mutation.updateAnimal",
+			map[string]interface{}{"DataSourceName": "AnimalsDataSource.Name"},
+			[]string{
+				`#set( $keyFields=["id"] )`,
+				`$util.error("Missing key field '$keyField' in update input", "ValidationError")`,
+				`$util.qr($key.put($keyField, $util.dynamodb.toDynamoDB($ctx.args.input.get($keyField))))`,
+				`$util.qr($expRemove.add("#$entry.key"))`,
+				`$util.qr($expSet.add("#$entry.key = :$entry.key"))`,
+				`$util.error("No fields to update", "ValidationError")`,
+				`#set( $expression = "SET" )`,
+				`#set( $expression = "$expression REMOVE" )`,
+				`"operation" : "UpdateItem", "key" : $util.toJson($key),`,
+				`"expression" : "attribute_exists(#keyExists)", "expressionNames" : { "#keyExists" : "id" }`,
+				`#if( $ctx.error.type == "DynamoDB:ConditionalCheckFailedException" ) $util.error("Mutation.updateAnimal failed: record to update does not exist", "NotFound")`,
+			},
+			[]string{"#version", "_version", "ConflictError"},
+			nil,
+		},
+		{
+			// Key attributes can't be filtered by a query, so only filters
+			// whose key conditions can be expressed query the table
+			"List by key",
+			dynamoQueryManifest,
+			"Query.listKittens",
+			nil,
+			[]string{
+				`#set( $hashKey = $util.defaultIfNull($filter.get("motherId"), {}) )`,
+				`#if( $hashKeyOperators.size() == 1 && $hashKeyOperators[0] == "eq" )`,
+				`#set( $sortKey = $util.defaultIfNull($filter.get("born"), {}) )`,
+				`#set( $expression = "$expression AND begins_with(#sortKey, :sortKey)" )`,
+				`#else ## ne, contains and notContains have no key condition #set( $query = false ) #end`,
+				`#elseif( $sortKeyOperators.size() > 1 ) #set( $query = false ) #end`,
+				`$util.qr($expressionNames.put("#sortKey", "born"))`,
+				`#if( $entry.key != "motherId" && $entry.key != "born" ) $util.qr($remaining.put($entry.key, $entry.value))`,
+				`#if( $query ) { "version" : "2017-02-28", "operation" : "Query",`,
+				`"filter": #if( !$remaining.isEmpty() ) $util.transform.toDynamoDBFilterExpression($remaining) #else null #end,`,
+				`#else { "version" : "2017-02-28", "operation" : "Scan",`,
+			},
+			[]string{`$entry.value.size() > 1`, `$remaining.remove(`},
+			nil,
+		},
+		{
+			"Versioned batch insert",
+			versionedManifest,
+			"Mutation.createAnimals",
+			nil,
+			[]string{`#foreach( $input in $ctx.args.input ) ## Versioned records start at version 1 $util.qr($input.put("version", 1)) $util.qr($items.add($util.dynamodb.toMapValues($input)))`},
+			nil,
+			nil,
+		},
+		{
+			"Versioned transact put",
+			versionedManifest,
+			"Mutation.importAnimal",
+			nil,
+			[]string{`## Versioned records start at version 1 $util.qr($attributes.put("version", 1)) $util.qr($transactItem.put("attributeValues", $util.dynamodb.toMapValues($attributes)))`},
+			nil,
+			nil,
+		},
+		{
+			"Batch insert with generated values",
+			generatedManifest,
+			"Mutation.createAnimals",
+			nil,
+			[]string{
+				`#set( $now = $util.time.nowISO8601() ) #foreach( $input in $ctx.args.input )`,
+				`$util.qr($input.put("id", $util.autoId())) $util.qr($input.put("createdAt", $now)) $util.qr($input.put("updatedAt", $now)) $util.qr($items.add($util.dynamodb.toMapValues($input)))`,
+			},
+			nil,
+			nil,
+		},
+		{
+			"Transact put with generated values",
+			generatedManifest,
+			"Mutation.importAnimal",
+			nil,
+			[]string{
+				`#set( $transactKeys = [] ) #set( $now = $util.time.nowISO8601() )`,
+				`#foreach( $item in $items ) ## Values generated by the server $util.qr($item.put("id", $util.autoId())) $util.qr($item.put("createdAt", $now)) $util.qr($item.put("updatedAt", $now)) #set( $keyFields = ["id"] )`,
+			},
+			nil,
+			nil,
+		},
+		{
+			"Publish with generated values",
+			generatedManifest,
+			"Mutation.animalBorn",
+			nil,
+			[]string{
+				`$util.qr($ctx.args.input.put("id", $util.autoId()))`,
+				`$util.qr($ctx.args.input.put("createdAt", $now))`,
+				`"detail": $util.toJson($ctx.args.input)`,
+			},
+			nil,
+			nil,
+		},
+	} {
+		var resolver *cloudFormationResource
+		for _, resource := range mustGenerateCloudFormation(t, c.manifest) {
+			if resource.Type == "AWS::AppSync::Resolver" && fmt.Sprintf("%v.%v", resource.Properties["TypeName"], resource.Properties["FieldName"]) == c.field {
+				resolver = &resource
+				break
+			}
+		}
+		if resolver == nil {
+			t.Errorf("%s: no resolver generated for %s", c.scenario, c.field)
+			continue
+		}
+
+		for name, value := range c.properties {
+			assert.Equal(t, value, resolver.Properties[name], "%s: %s", c.scenario, name)
+		}
+		// Templates naming tables have the environment substituted
+		for _, name := range []string{"RequestMappingTemplate", "ResponseMappingTemplate"} {
+			if strings.Contains(fmt.Sprint(resolver.Properties[name]), "${Environment}") {
+				assert.Equal(t, "!Sub", resolver.Tags[name], "%s: %s", c.scenario, name)
+			}
+		}
+		templates := collapse(fmt.Sprintf("%v\n%v", resolver.Properties["RequestMappingTemplate"], resolver.Properties["ResponseMappingTemplate"]))
+		for _, e := range c.expected {
+			assert.Contains(t, templates, collapse(e), c.scenario)
+		}
+		for _, u := range c.unexpected {
+			assert.NotContains(t, templates, collapse(u), c.scenario)
+		}
+
+		if len(c.terraform) > 0 {
+			file := collapse(string(mustGenerate(t, c.manifest)[terraformResolverFile(c.field)]))
+			for _, e := range c.terraform {
+				assert.Contains(t, file, collapse(e), c.scenario)
+			}
+		}
+	}
+}
+
+func TestGenerateUnkeyedSQLWrites(t *testing.T) {
+	for _, action := range []string{graphql.ActionInsert, graphql.ActionUpdate, graphql.ActionDelete} {
+		manifest := []byte(fmt.Sprintf(`
+sources:
+  default:
+    name: keepers
+    sql:
+      cluster_arn: cluster
+      secret_arn: secret
+      database: zoo
+objects:
+  - name: Keeper
+    fields:
+      - name: id
+        type: ID!
+mutations:
+  - name: changeKeeper
+    resolver:
+      action: %s
+      type: Keeper
+`, action))
+		expected := fmt.Sprintf("resolver 'Mutation_changeKeeper' must declare keyFields to %s rows of sql source 'keepers'", action)
+
+		for _, target := range []string{graphql.TargetTerraform, graphql.TargetCloudFormation} {
+			original := graphql.Target
+			graphql.Target = target
+			s := mustCompileSchema(t, manifest)
+			_, err := s.Generate()
+			graphql.Target = original
+
+			assert.Error(t, err, "%s: %s", action, target)
+			if assert.Len(t, s.Errors, 1, "%s: %s", action, target) {
+				assert.Contains(t, s.Errors[0].Error(), expected, "%s: %s", action, target)
+			}
+		}
+	}
+}
+
+func TestGenerateDynamoQuery(t *testing.T) {
+	files, err := mustCompileSchema(t, dynamoQueryManifest).Generate()
+	if err != nil {
+		t.Fatalf("error generating schema '%v', expected nil", err)
+	}
+
+	schema := string(files["schema.public.graphql"])
+	assert.Contains(t, schema, "litter(motherId: ID!, born: TableStringSortKeyCondition, limit: Int, nextToken: String, sortAscending: Boolean): KittenConnection!")
+	assert.Contains(t, schema, "input TableStringSortKeyCondition {\n\teq: String\n\tlt: String\n\tle: String\n\tgt: String\n\tge: String\n\tbetween: [String]\n\tbeginsWith: String\n}")
+	assert.Contains(t, schema, "type KittenConnection {")
+
+	query := string(files["_query_litter.tf"])
+	assert.Contains(t, query, `#set( $expressionNames = {"#hashKey": "motherId"} )`)
+	assert.Contains(t, query, `#set( $condition = $util.defaultIfNull($ctx.args.born, {}) )`)
+	assert.Contains(t, query, `#set( $expression = "$expression AND #sortKey BETWEEN :sortKeyFrom AND :sortKeyTo" )`)
+	assert.Contains(t, query, `#set( $expression = "$expression AND begins_with(#sortKey, :sortKey)" )`)
+	assert.Contains(t, query, `"scanIndexForward": #if( !$util.isNull($ctx.args.sortAscending) ) $ctx.args.sortAscending #else false #end,`)
+	assert.Contains(t, query, `"limit": $util.defaultIfNull($ctx.args.limit, 20),`)
+	assert.Contains(t, query, `"nextToken": $util.toJson($util.defaultIfNullOrBlank($context.result.nextToken, null))`)
+}
+
+var dynamoQueryManifest = []byte(`
+sources:
+  default:
+    name: kittens
+    dynamo:
+      hash_key:
+        name: motherId
+      sort_key:
+        name: born
+objects:
+  - name: Kitten
+    fields:
+      - name: motherId
+        type: ID!
+      - name: born
+        type: AWSDateTime!
+queries:
+  - name: litter
+    resolver:
+      action: query
+      type: [Kitten]
+      sortAscending: false
+      keyFields:
+        - name: motherId
+          type: ID
+        - name: born
+          type: String
+  - name: listKittens
+    resolver:
+      action: list
+      type: [Kitten]
+`)
+
+var searchManifest = []byte(`
+sources:
+  default:
+    name: animals_search
+    opensearch:
+      endpoint: https://search-zoo.eu-west-2.es.amazonaws.com
+      domain_arn: arn:aws:es:eu-west-2:123456789012:domain/zoo
+      index: animals
+objects:
+  - name: Animal
+    fields:
+      - name: id
+        type: ID!
+      - name: name
+        type: String
+queries:
+  - name: searchAnimals
+    resolver:
+      action: search
+      type: [Animal]
+`)
+
+var batchManifest = []byte(`
+sources:
+  default:
+    name: animals
+    dynamo:
+      hash_key:
+        name: id
+  keepers:
+    name: keepers
+    dynamo:
+      hash_key:
+        name: keeperId
+objects:
+  - name: Animal
+    fields:
+      - name: id
+        type: ID!
+      - name: keeperIds
+        type: [ID]
+      - name: keepers
+        resolver:
+          action: batch-get
+          type: [Keeper]
+          source: keepers
+          keyFields:
+            - name: keeperId
+              parent: keeperIds
+  - name: Keeper
+    fields:
+      - name: keeperId
+        type: ID!
+queries:
+  - name: getAnimals
+    resolver:
+      action: batch-get
+      type: [Animal]
+      keyFields:
+        - name: id
+          type: ID
+mutations:
+  - name: createKeepers
+    resolver:
+      action: batch-insert
+      type: [Keeper]
+      source: keepers
+  - name: deleteAnimals
+    resolver:
+      action: batch-delete
+      type: [Animal]
+      keyFields:
+        - name: id
+          type: ID
+`)
+
+var versionedManifest = []byte(`
+sources:
+  default:
+    name: animals
+    dynamo:
+      hash_key:
+        name: id
+  keepers:
+    name: keepers
+    dynamo:
+      hash_key:
+        name: id
+      conflict:
+        handler: AUTOMERGE
+objects:
+  - name: Animal
+    versioned: true
+    fields:
+      - name: id
+        type: ID!
+      - name: name
+  - name: Keeper
+    versioned: true
+    fields:
+      - name: id
+        type: ID!
+queries:
+  - name: getKeeper
+    resolver:
+      action: get
+      type: Keeper
+      source: keepers
+      keyFields:
+        - name: id
+          type: ID
+mutations:
+  - name: createAnimal
+    resolver:
+      action: insert
+      type: Animal
+  - name: updateAnimal
+    resolver:
+      action: update
+      type: Animal
+      keyFields:
+        - name: id
+  - name: deleteAnimal
+    resolver:
+      action: delete
+      type: Animal
+      keyFields:
+        - name: id
+          type: ID
+  - name: updateKeeper
+    resolver:
+      action: update
+      type: Keeper
+      source: keepers
+      keyFields:
+        - name: id
+  - name: deleteKeeper
+    resolver:
+      action: delete
+      type: Keeper
+      source: keepers
+      keyFields:
+        - name: id
+          type: ID
+  - name: createAnimals
+    resolver:
+      action: batch-insert
+      type: [Animal]
+  - name: importAnimal
+    resolver:
+      action: transact
+      type: Animal
+      transact:
+        - operation: put
+`)
+
+var generatedManifest = []byte(`
+sources:
+  default:
+    name: animals
+    dynamo:
+      hash_key:
+        name: id
+  keepers:
+    name: keepers
+    sql:
+      cluster_arn: cluster
+      secret_arn: secret
+      database: zoo
+  events:
+    name: zoo_events
+    eventbridge:
+      bus_arn: arn:aws:events:eu-west-2:123456789012:event-bus/zoo
+      source: com.example.zoo
+objects:
+  - name: Animal
+    fields:
+      - name: id
+        type: ID!
+        generated: uuid
+      - name: name
+      - name: createdAt
+        type: AWSDateTime
+        generated: createdAt
+      - name: updatedAt
+        type: AWSDateTime
+        generated: updatedAt
+  - name: Keeper
+    fields:
+      - name: id
+        type: ID!
+        generated: ulid
+  - name: Toy
+    fields:
+      - name: id
+        type: ID!
+      - name: ref
+        type: ID
+        generated: uuid
+      - name: createdAt
+        generated: createdAt
+mutations:
+  - name: createAnimal
+    resolver:
+      action: insert
+      type: Animal
+      keyFields:
+        - name: id
+  - name: updateAnimal
+    resolver:
+      action: update
+      type: Animal
+      keyFields:
+        - name: id
+  - name: createKeeper
+    resolver:
+      action: insert
+      type: Keeper
+      source: keepers
+      keyFields:
+        - name: id
+  - name: createToy
+    resolver:
+      action: insert
+      type: Toy
+      keyFields:
+        - name: id
+  - name: createAnimals
+    resolver:
+      action: batch-insert
+      type: [Animal]
+  - name: importAnimal
+    resolver:
+      action: transact
+      type: Animal
+      transact:
+        - operation: put
+  - name: animalBorn
+    resolver:
+      action: publish
+      type: Animal
+      source: events
+`)
+
+var updateManifest = []byte(`
+sources:
+  default:
+    name: animals
+    dynamo:
+      hash_key:
+        name: id
+objects:
+  - name: Animal
+    fields:
+      - name: id
+        type: ID!
+      - name: name
+      - name: enclosure
+mutations:
+  - name: updateAnimal
+    resolver:
+      action: update
+      type: Animal
+      keyFields:
+        - name: id
+`)
@@ -2,11 +2,13 @@ package graphql
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"text/template"
 	"time"
 
@@ -17,24 +19,61 @@ import (
 // GeneratedFilesPath defines where to put files created from parsing the schema
 var GeneratedFilesPath = "./generated"
 
+// Supported output targets
+const (
+	TargetTerraform      = "terraform"
+	TargetCloudFormation = "cloudformation"
+)
+
+// Target defines the infrastructure format to generate: terraform (one file
+// per resolver and data source) or cloudformation (a single template)
+var Target = TargetTerraform
+
+// Reproducible defines whether generated output is byte for byte reproducible.
+// When set, generated files are stamped with a hash of the manifest they were
+// generated from rather than the time they were generated.
+var Reproducible = true
+
 var schema Schema
 
 type (
 	// Schema represents the elements of a graphql schema
 	Schema struct {
+		API       *API        `yaml:"api"`
 		Enums     []*Enum     `yaml:"enums"`
 		Objects   []*Object   `yaml:"objects"`
 		Queries   []*Query    `yaml:"queries"`
 		Mutations []*Mutation `yaml:"mutations"`
 
+		Subscriptions []*Subscription `yaml:"subscriptions"`
+
+		// Functions available to pipeline resolvers
+		Functions []*Function `yaml:"functions"`
+
 		Sources map[string]*Source `yaml:"sources"`
 
+		// Directory of resolver templates overriding the built-in templates
+		Templates string `yaml:"templates"`
+
 		// Automatically populated to create
 		// filtering options for list types
 		FilterInputs []string
 
 		// Connection objects to be built - populated automatically by "list" resolvers
-		Connections   []string
+		Connections []string
+
+		// Search connection objects to be built - populated automatically by
+		// "search" resolvers
+		SearchConnections []string
+
+		// Sort key condition inputs to be built, by scalar type - populated
+		// automatically by "query" resolvers
+		SortKeyConditions []string
+
+		// Batch result objects to be built - populated automatically by
+		// "batch-*" resolvers
+		BatchResults []string
+
 		FilterObjects FilterObjectList
 		InputObjects  InputObjectList
 
@@ -42,6 +81,7 @@ type (
 		Errors []error
 
 		objectLookup map[string]*Object
+		manifest     []byte
 		// dataSourceType string
 	}
 )
@@ -57,18 +97,37 @@ func NewSchemaFromManifest(manifest []byte) (*Schema, error) {
 	s.FilterInputs = []string{"Int", "String", "Float", "ID"}
 	s.Errors = []error{}
 	s.Connections = []string{}
+	s.SearchConnections = []string{}
+	s.SortKeyConditions = []string{}
+	s.BatchResults = []string{}
+
+	s.manifest = manifest
+
+	if s.API == nil {
+		s.API = &API{Name: defaultAPIName}
+	}
 
 	s.objectLookup = make(map[string]*Object)
 	for _, o := range s.Objects {
+		if err := o.addVersionField(); err != nil {
+			return nil, err
+		}
 		s.objectLookup[o.Name] = o
 	}
 	return &s, nil
 }
 
 var funcMap = template.FuncMap{
-	"now": func() string {
-		return time.Now().String()
-	},
+	"tableName": tableName,
+}
+
+// header returns the comment block written at the top of each generated file
+func (s *Schema) header() string {
+	stamp := fmt.Sprintf("Generated from manifest sha256:%x", sha256.Sum256(s.manifest))
+	if !Reproducible {
+		stamp = "Generated at " + time.Now().String()
+	}
+	return "## !NOTE: This file is auto-generated DO NOT EDIT\n## " + stamp + "\n"
 }
 
 // GenerateBytes renders the schema to a bytes buffer ready to be written to
@@ -103,6 +162,11 @@ func (s *Schema) CleanOutput() error {
 }
 
 func setDataSource(r *Resolver, s *Schema) error {
+	// Each function of a pipeline has its own data source
+	if r.IsPipeline() {
+		return nil
+	}
+
 	if ds, ok := s.Sources[r.SourceKey]; ok {
 		r.DataSource = ds
 		return nil
@@ -116,19 +180,141 @@ func setDataSource(r *Resolver, s *Schema) error {
 	return fmt.Errorf("resolver '%s_%s' has unknown data source '%s'", r.Parent, r.FieldName, r.SourceKey)
 }
 
+func setObject(r *Resolver, s *Schema) {
+	r.API = s.API
+	if r.Type == nil {
+		return
+	}
+	r.Object = s.objectLookup[r.Type.Name]
+}
+
 func (s *Schema) addError(err error) {
 	s.Errors = append(s.Errors, err)
 }
 
+func (s *Schema) addConnection(name string) {
+	for _, c := range s.Connections {
+		if c == name {
+			return
+		}
+	}
+	s.Connections = append(s.Connections, name)
+}
+
+func (s *Schema) addSortKeyCondition(scalar string) {
+	for _, c := range s.SortKeyConditions {
+		if c == scalar {
+			return
+		}
+	}
+	s.SortKeyConditions = append(s.SortKeyConditions, scalar)
+}
+
+func (s *Schema) addBatchResult(name string) {
+	for _, c := range s.BatchResults {
+		if c == name {
+			return
+		}
+	}
+	s.BatchResults = append(s.BatchResults, name)
+}
+
+func (s *Schema) addSearchConnection(name string) {
+	for _, c := range s.SearchConnections {
+		if c == name {
+			return
+		}
+	}
+	s.SearchConnections = append(s.SearchConnections, name)
+}
+
 // WriteAll outputs the generated public schema and any resolver files to the
-// location given by `GeneratedFilesPath`
+// location given by `GeneratedFilesPath`. Nothing is removed from the output
+// path unless generation succeeds.
 func (s *Schema) WriteAll() error {
+	files, err := s.Generate()
+	if err != nil {
+		return err
+	}
 
 	if err := s.CleanOutput(); err != nil {
 		return err
 	}
 
+	names := []string{}
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		path := filepath.Join(GeneratedFilesPath, name)
+		if err := ioutil.WriteFile(path, files[name], 0644); err != nil {
+			return errors.Wrap(err, "failed to write schema file")
+		}
+		log.Printf("written: %s", path)
+	}
+	return nil
+}
+
+// Generate renders the public schema and any resolver and data source files
+// in memory, without touching the filesystem. The rendered content is
+// returned keyed by the name of the file it would be written to.
+func (s *Schema) Generate() (map[string][]byte, error) {
+	resolvers, err := s.prepare()
+	if err != nil {
+		return nil, err
+	}
+
 	toWrite := []schemaFileWriter{}
+	switch Target {
+	case TargetTerraform:
+		if s.API.Create {
+			toWrite = append(toWrite, s.API)
+		}
+		for _, f := range s.Functions {
+			toWrite = append(toWrite, f)
+		}
+		for _, r := range resolvers {
+			toWrite = append(toWrite, r)
+		}
+		for _, ds := range s.sortedSources() {
+			toWrite = append(toWrite, ds)
+		}
+	case TargetCloudFormation:
+		toWrite = append(toWrite, &cloudFormation{schema: s, resolvers: resolvers})
+	default:
+		return nil, fmt.Errorf("unsupported target '%s'", Target)
+	}
+
+	// Add the schema to write of course!
+	toWrite = append(toWrite, s)
+
+	files := map[string][]byte{}
+	for _, w := range toWrite {
+		bb, err := w.GenerateBytes()
+		if err != nil {
+			s.addError(errors.Wrapf(err, "failed to generate content for '%s'", w.OutputName()))
+			continue
+		}
+		files[w.OutputName()] = append([]byte(s.header()), bb...)
+	}
+
+	if len(s.Errors) > 0 {
+		return nil, errors.New("errors occurred during generation")
+	}
+	return files, nil
+}
+
+// prepare links each resolver to its data source and returned object, and
+// creates the connection, filter and input objects they require. It returns
+// every resolver in the schema.
+func (s *Schema) prepare() ([]*Resolver, error) {
+	resolvers := []*Resolver{}
+
+	if err := s.prepareFunctions(); err != nil {
+		return nil, err
+	}
 
 	for _, q := range s.Queries {
 		if r := q.Resolver; r != nil {
@@ -136,12 +322,17 @@ func (s *Schema) WriteAll() error {
 			r.FieldName = q.Name
 			r.ArgsSource = "args"
 			if err := setDataSource(r, s); err != nil {
-				return err
+				return nil, err
 			}
+			setObject(r, s)
 
 			// Create appropriate input and connection objects
-			if r.Action == ActionList {
-				s.Connections = append(s.Connections, r.Type.Name)
+			if r.Action == ActionList || r.Action == ActionSearch {
+				if r.Action == ActionList {
+					s.addConnection(r.Type.Name)
+				} else {
+					s.addSearchConnection(r.Type.Name)
+				}
 				o, ok := s.objectLookup[r.Type.Name]
 				if !ok {
 					s.addError(fmt.Errorf("unknown type '%s' when attempting to create filter object", r.Type.Name))
@@ -149,8 +340,29 @@ func (s *Schema) WriteAll() error {
 				}
 				s.AddFilterFromObject(o)
 			}
+			if r.IsBatch() {
+				s.addBatchResult(r.Type.Name)
+				s.addKeyInput(r)
+			}
+			if r.Action == ActionQuery {
+				s.addConnection(r.Type.Name)
+				if r.SortKeyCondition() != "" {
+					s.addSortKeyCondition(r.KeyFields[1].Type.Name)
+				}
+			}
+			if action := r.inputAction(); action != "" {
+				o, ok := s.objectLookup[r.Type.Name]
+				if !ok {
+					s.addError(fmt.Errorf("unknown type '%s' when attempting to create input object", r.Type.Name))
+					continue
+				}
+				if err := s.AddInputFromObject(o, action); err != nil {
+					s.addError(errors.Wrap(err, "failed to create input object"))
+					continue
+				}
+			}
 
-			toWrite = append(toWrite, r)
+			resolvers = append(resolvers, r)
 		}
 	}
 
@@ -160,8 +372,12 @@ func (s *Schema) WriteAll() error {
 			r.FieldName = m.Name
 			r.ArgsSource = "args"
 			if err := setDataSource(r, s); err != nil {
-				return err
+				return nil, err
+			}
+			if err := setTransactSources(r, s); err != nil {
+				return nil, err
 			}
+			setObject(r, s)
 
 			// Create appropriate input objects
 			o, ok := s.objectLookup[r.Type.Name]
@@ -169,18 +385,27 @@ func (s *Schema) WriteAll() error {
 				s.addError(fmt.Errorf("unknown type '%s' when attempting to create input object", r.Type.Name))
 				continue
 			}
-			err := s.AddInputFromObject(o, r.Action)
-			if err != nil {
-				s.addError(errors.Wrap(err, "failed to create input object"))
-				continue
+			if r.IsBatch() {
+				s.addBatchResult(r.Type.Name)
+				if r.Action != ActionBatchInsert {
+					s.addKeyInput(r)
+				}
 			}
 
-			toWrite = append(toWrite, r)
+			// Only inserts, updates and events take an input object.
+			// Other actions take the key fields as arguments.
+			if action := r.inputAction(); action != "" {
+				err := s.AddInputFromObject(o, action)
+				if err != nil {
+					s.addError(errors.Wrap(err, "failed to create input object"))
+					continue
+				}
+			}
+
+			resolvers = append(resolvers, r)
 		}
 	}
 
-	// connections := map[string]bool{}
-
 	for _, o := range s.Objects {
 		for _, f := range o.Fields {
 			if r := f.Resolver; r != nil {
@@ -188,53 +413,82 @@ func (s *Schema) WriteAll() error {
 				r.FieldName = f.Name
 				r.ArgsSource = "source"
 				if err := setDataSource(r, s); err != nil {
-					return err
+					return nil, err
 				}
+				setObject(r, s)
 
 				// Create appropriate input and connection objects
 				if r.Action == ActionList {
 					if _, ok := s.objectLookup[r.Type.Name]; !ok {
 						// Omit if already created with the objects
-						// TODO better hash map of connections
-						s.Connections = append(s.Connections, r.Type.Name)
-						// connections[r.Type.Name] = true
+						s.addConnection(r.Type.Name)
 					}
 				}
 
 				// TODO - filters
 
-				toWrite = append(toWrite, r)
+				resolvers = append(resolvers, r)
 			}
 		}
 	}
 
-	for _, ds := range s.Sources {
-		toWrite = append(toWrite, ds)
+	for _, sub := range s.Subscriptions {
+		if len(sub.Mutations) == 0 || s.mutation(sub.Mutations[0]) == nil {
+			s.addError(fmt.Errorf("subscription '%s' does not subscribe to a known mutation", sub.Name))
+			continue
+		}
+		// Return the same type as the mutations unless told otherwise
+		if sub.Type == nil {
+			if m := s.mutation(sub.Mutations[0]); m.Resolver != nil {
+				sub.Type = &FieldType{Name: m.Resolver.Type.Name}
+			}
+		}
+
+		if sub.EnhancedFiltering {
+			ds, err := s.noneSource()
+			if err != nil {
+				return nil, err
+			}
+			r := &Resolver{
+				Action:     ActionSubscribe,
+				Type:       sub.Type,
+				Parent:     "Subscription",
+				FieldName:  sub.Name,
+				ArgsSource: "args",
+				DataSource: ds,
+			}
+			setObject(r, s)
+			sub.Resolver = r
+			resolvers = append(resolvers, r)
+		}
 	}
 
-	// Add the schema to write of course!
-	toWrite = append(toWrite, s)
+	for _, ds := range s.Sources {
+		ds.API = s.API
+	}
 
-	errored := make(chan error)
-	names := make(chan string)
+	sort.Strings(s.Connections)
+	sort.Strings(s.SearchConnections)
+	sort.Strings(s.SortKeyConditions)
+	sort.Strings(s.BatchResults)
 
-	for _, w := range toWrite {
-		go write(w, errored, names)
-	}
+	return resolvers, nil
+}
 
-	for i := 0; i < len(toWrite); i++ {
-		select {
-		case err := <-errored:
-			s.Errors = append(s.Errors, err)
-		case name := <-names:
-			log.Printf("written: %s", name)
-		}
+// sortedSources returns the data sources ordered by their key. They're held
+// in a map so must be sorted to give a stable order.
+func (s *Schema) sortedSources() []*Source {
+	keys := make([]string, 0, len(s.Sources))
+	for k := range s.Sources {
+		keys = append(keys, k)
 	}
+	sort.Strings(keys)
 
-	if len(s.Errors) > 0 {
-		return errors.New("errors occurred during generation")
+	sources := make([]*Source, len(keys))
+	for i, k := range keys {
+		sources[i] = s.Sources[k]
 	}
-	return nil
+	return sources
 }
 
 // OutputName returns the file name to be written for the schema
@@ -246,18 +500,3 @@ type schemaFileWriter interface {
 	GenerateBytes() ([]byte, error)
 	OutputName() string
 }
-
-func write(w schemaFileWriter, e chan error, d chan string) {
-	bb, err := w.GenerateBytes()
-	if err != nil {
-		e <- errors.Wrap(err, "failed to generate content")
-		return
-	}
-
-	path := filepath.Join(GeneratedFilesPath, w.OutputName())
-
-	if err := ioutil.WriteFile(path, bb, 0644); err != nil {
-		e <- errors.Wrap(err, "failed to write schema file")
-	}
-	d <- path
-}
@@ -3,8 +3,6 @@ package graphql
 import "text/template"
 
 var schemaTemplate = template.Must(template.New("schema").Funcs(funcMap).Parse(`
-## !NOTE: This file is auto-generated DO NOT EDIT
-## Generated at {{now}}
 {{define "field" -}}
 {{.Name}}: {{if .Type.IsList}}[{{end -}}
 	{{.Type.Name}}{{if .Type.NonNullable}}!{{end}}
@@ -12,7 +10,7 @@ var schemaTemplate = template.Must(template.New("schema").Funcs(funcMap).Parse(`
 {{- end}}
 
 {{define "resolver" -}}
-{{.Name}}{{ .Resolver.KeyFieldArgsString }}: {{ if eq .Resolver.Action "get-items" }}[{{end}}{{.Resolver.Type.Name -}}{{ if eq .Resolver.Action "get-items" }}]{{end}}{{ if eq .Resolver.Action "list" }}Connection!{{ end }}
+{{.Name}}{{ .Resolver.KeyFieldArgsString }}: {{ if eq .Resolver.Action "get-items" }}[{{end}}{{.Resolver.Type.Name -}}{{ if eq .Resolver.Action "get-items" }}]{{end}}{{ if or (eq .Resolver.Action "list") (eq .Resolver.Action "query") }}Connection!{{ end }}{{ if eq .Resolver.Action "search" }}SearchConnection!{{ end }}{{ if .Resolver.IsBatch }}BatchResult!{{ end }}{{ .Auth.Directives }}
 {{- end}}
 
 {{- range .Enums}}enum {{.Name}} {
@@ -21,19 +19,46 @@ var schemaTemplate = template.Must(template.New("schema").Funcs(funcMap).Parse(`
 }
 {{end}}
 
-{{- range .Objects}}type {{.Name}} {
-    {{range .Fields}}{{template "field" .}}
+{{- range .Objects}}type {{.Name}}{{ .Auth.Directives }} {
+    {{range .Fields}}{{template "field" .}}{{ .Auth.Directives }}
     {{end}}
 }
 {{end}}
 
 {{- range .Connections}}
-type {{.}}Connection {
+type {{.}}Connection{{ $.ConnectionDirectives . }} {
 	items: [{{.}}]
 	nextToken: String
 }
 {{ end -}}
 
+{{- range .SearchConnections}}
+type {{.}}SearchConnection{{ $.ConnectionDirectives . }} {
+	items: [{{.}}]
+	total: Int
+	nextToken: String
+}
+{{ end -}}
+
+{{- range .BatchResults}}
+type {{.}}BatchResult{{ $.ConnectionDirectives . }} {
+	items: [{{.}}]
+	unprocessedKeys: [AWSJSON]
+}
+{{ end -}}
+
+{{- if .SearchConnections}}
+enum SearchSortDirection {
+	asc
+	desc
+}
+
+input SearchSortInput {
+	field: String!
+	direction: SearchSortDirection
+}
+{{ end -}}
+
 {{- range .FilterObjects }}
 input {{ .Name }} {
 	{{ range .Fields -}}{{template "field" .}}
@@ -64,6 +89,14 @@ type Mutation {
 }
 {{end -}}
 
+{{- if .Subscriptions}}
+type Subscription {
+	{{- range .Subscriptions}}
+	{{ .Name }}{{ .ArgsString }}: {{ .Type.Name }} @aws_subscribe(mutations: {{ .MutationsString }}){{ .Auth.Directives }}
+	{{- end}}
+}
+{{end -}}
+
 input TableBooleanFilterInput {
 	ne: Boolean
 	eq: Boolean
@@ -80,4 +113,16 @@ input TableBooleanFilterInput {
 	between: [{{.}}]
 }
 {{end}}
+{{- range .SortKeyConditions}}input Table{{.}}SortKeyCondition {
+	eq: {{.}}
+	lt: {{.}}
+	le: {{.}}
+	gt: {{.}}
+	ge: {{.}}
+	between: [{{.}}]
+	{{- if or (eq . "String") (eq . "ID") }}
+	beginsWith: {{.}}
+	{{- end }}
+}
+{{end}}
 `))
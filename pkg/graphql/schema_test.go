@@ -1,6 +1,7 @@
 package graphql_test
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/ONSdigital/aws-appsync-generator/pkg/graphql"
@@ -8,13 +9,46 @@ import (
 )
 
 func mustCompileSchema(t *testing.T, manifest []byte) *graphql.Schema {
-	s, err := graphql.NewSchemaFromManifest(exampleSchemaManifest)
+	s, err := graphql.NewSchemaFromManifest(manifest)
 	if err != nil {
 		t.Fatalf("unable to parse manifest: %v", err)
 	}
 	return s
 }
 
+func mustGenerate(t *testing.T, manifest []byte) map[string][]byte {
+	files, err := mustCompileSchema(t, manifest).Generate()
+	if err != nil {
+		t.Fatalf("error generating schema '%v', expected nil", err)
+	}
+	return files
+}
+
+// schemaFields parses the generated schema into the fields of its types,
+// inputs and enums keyed by "Type.field", so tests don't depend on its layout
+func schemaFields(t *testing.T, files map[string][]byte) map[string]string {
+	fields := map[string]string{}
+	parent := ""
+	for _, line := range strings.Split(string(files["schema.public.graphql"]), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasSuffix(line, "{"):
+			words := strings.Fields(line)
+			if len(words) < 2 {
+				t.Fatalf("unexpected schema definition '%s'", line)
+			}
+			parent = words[1]
+		case line == "}":
+			parent = ""
+		case parent != "":
+			name := strings.FieldsFunc(line, func(r rune) bool { return r == '(' || r == ':' || r == ' ' })[0]
+			fields[parent+"."+name] = line
+		}
+	}
+	return fields
+}
+
 func TestNewSchemaFromManifest(t *testing.T) {
 	_, err := graphql.NewSchemaFromManifest(exampleSchemaManifest)
 	if err != nil {
@@ -31,6 +65,111 @@ func TestSchemaGenerateBytes(t *testing.T) {
 	assert.IsType(t, []byte{}, g)
 }
 
+func TestSchemaGenerateIsReproducible(t *testing.T) {
+	first, err := mustCompileSchema(t, reproducibleSchemaManifest).Generate()
+	if err != nil {
+		t.Fatalf("error generating schema '%v', expected nil", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		again, err := mustCompileSchema(t, reproducibleSchemaManifest).Generate()
+		if err != nil {
+			t.Fatalf("error generating schema '%v', expected nil", err)
+		}
+		assert.Equal(t, first, again)
+	}
+
+	schema := string(first["schema.public.graphql"])
+	assert.Contains(t, schema, "## Generated from manifest sha256:")
+	assert.NotContains(t, schema, "Generated at")
+}
+
+func TestGenerateSchemaFields(t *testing.T) {
+	for _, c := range []struct {
+		scenario   string
+		manifest   []byte
+		expected   map[string]string
+		unexpected []string
+	}{
+		{
+			"Search connections, sorting and filters",
+			searchManifest,
+			map[string]string{
+				"Query.searchAnimals":              "searchAnimals(filter: AnimalFilter, sort: [SearchSortInput], from: Int, size: Int, nextToken: String): AnimalSearchConnection!",
+				"AnimalSearchConnection.items":     "items: [Animal]",
+				"AnimalSearchConnection.total":     "total: Int",
+				"AnimalSearchConnection.nextToken": "nextToken: String",
+				"SearchSortInput.field":            "field: String!",
+				"SearchSortInput.direction":        "direction: SearchSortDirection",
+				"AnimalFilter.name":                "name: TableStringFilterInput",
+			},
+			nil,
+		},
+		{
+			"Published and local mutations",
+			eventBridgeManifest,
+			map[string]string{
+				"Mutation.animalFed":   "animalFed(input: CreateAnimalInput): Animal",
+				"Mutation.animalNamed": "animalNamed(input: CreateAnimalInput): Animal",
+				"Mutation.animalMoved": "animalMoved(id:ID,enclosure:String): Animal",
+			},
+			nil,
+		},
+		{
+			"Batch results and keys",
+			batchManifest,
+			map[string]string{
+				"AnimalBatchResult.items":           "items: [Animal]",
+				"AnimalBatchResult.unprocessedKeys": "unprocessedKeys: [AWSJSON]",
+				"AnimalKeyInput.id":                 "id: ID!",
+				"Query.getAnimals":                  "getAnimals(keys: [AnimalKeyInput!]!): AnimalBatchResult!",
+				"Mutation.createKeepers":            "createKeepers(input: [CreateKeeperInput!]!): KeeperBatchResult!",
+				"Mutation.deleteAnimals":            "deleteAnimals(keys: [AnimalKeyInput!]!): AnimalBatchResult!",
+				"Animal.keepers":                    "keepers: [Keeper]",
+			},
+			[]string{"AnimalKeyInput.keeperIds"},
+		},
+		{
+			"Versioned objects",
+			versionedManifest,
+			map[string]string{
+				"Animal.version":            "version: Int!",
+				"CreateAnimalInput.id":      "id: ID",
+				"CreateAnimalInput.name":    "name: String",
+				"UpdateAnimalInput.version": "version: Int!",
+				"Mutation.deleteAnimal":     "deleteAnimal(id: ID!, expectedVersion: Int!): Animal",
+			},
+			[]string{"CreateAnimalInput.version"},
+		},
+		{
+			"Generated fields",
+			generatedManifest,
+			map[string]string{
+				"Animal.createdAt":       "createdAt: AWSDateTime",
+				"Animal.updatedAt":       "updatedAt: AWSDateTime",
+				"CreateAnimalInput.name": "name: String",
+				"UpdateAnimalInput.id":   "id: ID",
+				"UpdateAnimalInput.name": "name: String",
+			},
+			[]string{
+				"CreateAnimalInput.id",
+				"CreateAnimalInput.createdAt",
+				"CreateAnimalInput.updatedAt",
+				"UpdateAnimalInput.createdAt",
+				"UpdateAnimalInput.updatedAt",
+			},
+		},
+	} {
+		fields := schemaFields(t, mustGenerate(t, c.manifest))
+		for name, field := range c.expected {
+			assert.Equal(t, field, fields[name], "%s: %s", c.scenario, name)
+		}
+		for _, name := range c.unexpected {
+			assert.NotContains(t, fields, name, c.scenario)
+		}
+	}
+}
+
 var exampleSchemaManifest = []byte(`
 enums:
   - name: Channel
@@ -48,3 +187,35 @@ objects:
       - name: copiedTo
         type: [String]
 `)
+
+var reproducibleSchemaManifest = []byte(`
+sources:
+  people:
+    name: people
+    dynamo:
+      hash_key:
+        name: id
+  places:
+    name: places
+    dynamo:
+      hash_key:
+        name: id
+  things:
+    name: things
+    sql:
+      cluster_arn: cluster
+      secret_arn: secret
+      database: db
+
+enums:
+  - name: Channel
+    values: [EMAIL,LETTER,OTHER]
+
+objects:
+  - name: Correspondence
+    fields:
+      - name: reference
+        type: ID!
+      - name: channel
+        type: Channel
+`)
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"net/url"
 	"regexp"
 	"strings"
 	"text/template"
@@ -15,9 +16,16 @@ type (
 		Name   string        `yaml:"name"`
 		Dynamo *DynamoSource `yaml:"dynamo"`
 		SQL    *SQLSource    `yaml:"sql"`
+		Lambda *LambdaSource `yaml:"lambda"`
+		HTTP   *HTTPSource   `yaml:"http"`
+
+		OpenSearch  *OpenSearchSource  `yaml:"opensearch"`
+		EventBridge *EventBridgeSource `yaml:"eventbridge"`
+		None        *NoneSource        `yaml:"none"`
 
 		// Set automatically
 		Type string
+		API  *API
 	}
 
 	// DynamoKeyType represents a key with type
@@ -31,18 +39,202 @@ type (
 		HashKey *DynamoKeyType `yaml:"hash_key"`
 		SortKey *DynamoKeyType `yaml:"sort_key,omitempty"`
 		Backup  bool           `yaml:"backup,omitempty"`
+
+		// Secondary indexes of the table
+		GSI []*DynamoIndex `yaml:"gsi,omitempty"`
+		LSI []*DynamoIndex `yaml:"lsi,omitempty"`
+
+		// AppSync conflict detection for the versioned objects of the table
+		Conflict *DynamoConflict `yaml:"conflict,omitempty"`
+
+		// Items expire at the time held by the ttl attribute
+		TTL *DynamoTTL `yaml:"ttl,omitempty"`
+
+		// PAY_PER_REQUEST or PROVISIONED. Default PAY_PER_REQUEST
+		BillingMode string `yaml:"billing_mode,omitempty"`
+
+		// Capacity of a PROVISIONED table and its global indexes
+		Capacity *DynamoCapacity `yaml:"capacity,omitempty"`
+
+		// Encrypts the table with a KMS key rather than one owned by dynamo
+		Encryption *DynamoEncryption `yaml:"encryption,omitempty"`
+
+		// View type of the table's stream - KEYS_ONLY, NEW_IMAGE, OLD_IMAGE or
+		// NEW_AND_OLD_IMAGES. The table has no stream where not set
+		Stream string `yaml:"stream,omitempty"`
+
+		// STANDARD or STANDARD_INFREQUENT_ACCESS. Default STANDARD
+		TableClass string `yaml:"table_class,omitempty"`
+
+		// Stops the table being deleted until the protection is removed
+		DeletionProtection bool `yaml:"deletion_protection,omitempty"`
+
+		// Tags added to the Environment and Name tags of the table
+		Tags map[string]string `yaml:"tags,omitempty"`
+	}
+
+	// DynamoCapacity is the capacity provisioned for a table
+	DynamoCapacity struct {
+		Read  int `yaml:"read"`
+		Write int `yaml:"write"`
+
+		// Scales the capacity between that provisioned and a maximum
+		Autoscaling *DynamoAutoscaling `yaml:"autoscaling,omitempty"`
+	}
+
+	// DynamoAutoscaling scales the capacity of a table to keep its use at a
+	// target
+	DynamoAutoscaling struct {
+		MaxRead  int `yaml:"max_read"`
+		MaxWrite int `yaml:"max_write"`
+
+		// Percentage of the capacity to aim to use, from 20 to 90. Default 70
+		TargetUtilization int `yaml:"target_utilization,omitempty"`
+	}
+
+	// DynamoEncryption encrypts a table with a KMS key
+	DynamoEncryption struct {
+		// ARN of a customer managed key. Defaults to the AWS managed key
+		KMSKeyARN string `yaml:"kms_key_arn,omitempty"`
+	}
+
+	// DynamoTTL enables time to live on a dynamo table
+	DynamoTTL struct {
+		// Name of the attribute holding the time the item expires, in epoch
+		// seconds
+		Attribute string `yaml:"attribute"`
+	}
+
+	// DynamoConflict enables AppSync conflict detection and resolution on a
+	// dynamo source. The data source is versioned, with a delta sync table,
+	// and the resolvers writing versioned objects detect conflicts by version
+	DynamoConflict struct {
+		// One of OPTIMISTIC_CONCURRENCY, AUTOMERGE or LAMBDA
+		Handler string `yaml:"handler"`
+
+		// ARN of the function resolving conflicts where the handler is LAMBDA
+		LambdaARN string `yaml:"lambda_arn,omitempty"`
+
+		// Minutes deleted items are kept in the table. Default 43200
+		BaseTableTTL int `yaml:"base_table_ttl,omitempty"`
+
+		// Minutes changes are kept in the delta sync table. Default 1440
+		DeltaSyncTableTTL int `yaml:"delta_sync_table_ttl,omitempty"`
+	}
+
+	// DynamoIndex represents a global or local secondary index of a dynamo
+	// table. Local indexes share the hash key of the table, so only declare
+	// a sort key
+	DynamoIndex struct {
+		Name    string         `yaml:"name"`
+		HashKey *DynamoKeyType `yaml:"hash_key,omitempty"`
+		SortKey *DynamoKeyType `yaml:"sort_key,omitempty"`
+
+		// Attributes projected into the index: ALL, KEYS_ONLY or INCLUDE.
+		// Defaults to ALL
+		Projection string `yaml:"projection,omitempty"`
+
+		// Attributes projected where the projection is INCLUDE
+		NonKeyAttributes []string `yaml:"non_key_attributes,omitempty"`
 	}
 
-	// SQLSource represents a sql based db data source
+	// SQLSource represents a sql based db data source. It is accessed through
+	// the data api of an aurora serverless cluster
 	SQLSource struct {
 		PrimaryKey string `yaml:"primary_key"`
-		// TODO other fields
+		ClusterARN string `yaml:"cluster_arn"`
+		SecretARN  string `yaml:"secret_arn"`
+		Database   string `yaml:"database"`
+
+		// Name of the table to query. Defaults to the name of the source
+		Table string `yaml:"table,omitempty"`
+	}
+
+	// LambdaSource represents a lambda function data source. The function is
+	// either given by its ARN or, where it is managed in the same terraform
+	// module, by the name of its `aws_lambda_function` resource
+	LambdaSource struct {
+		FunctionARN string `yaml:"function_arn,omitempty"`
+		Function    string `yaml:"function,omitempty"`
+	}
+
+	// HTTPSource represents an http endpoint data source, e.g. a REST service
+	HTTPSource struct {
+		// Scheme and host of the service, e.g. https://api.example.com
+		Endpoint string `yaml:"endpoint"`
+
+		// (Optional) Sign requests to the endpoint with the api's service role
+		IAM *HTTPIAMConfig `yaml:"iam,omitempty"`
+	}
+
+	// HTTPIAMConfig is the configuration for signing http requests. The
+	// api's service role is allowed the actions on the resources
+	HTTPIAMConfig struct {
+		SigningRegion  string `yaml:"signing_region"`
+		SigningService string `yaml:"signing_service"`
+
+		// (Optional) Actions allowed on the service. Defaults to
+		// execute-api:Invoke where the service is execute-api
+		Actions []string `yaml:"actions,omitempty"`
+
+		// ARNs of the resources the actions are allowed on, e.g. the
+		// execution ARN of an api gateway stage
+		Resources []string `yaml:"resources,omitempty"`
+	}
+
+	// OpenSearchSource represents an index of an opensearch domain
+	OpenSearchSource struct {
+		// Endpoint of the domain, e.g.
+		// https://search-zoo-abc123.eu-west-2.es.amazonaws.com
+		Endpoint string `yaml:"endpoint"`
+
+		// ARN of the domain, used to allow the api to search it
+		DomainARN string `yaml:"domain_arn"`
+
+		// (Optional) Region of the domain. Defaults to the region of the api
+		Region string `yaml:"region,omitempty"`
+
+		// Name of the index to search. Defaults to the name of the source
+		Index string `yaml:"index,omitempty"`
+	}
+
+	// EventBridgeSource represents an eventbridge event bus that resolvers
+	// publish events to
+	EventBridgeSource struct {
+		// ARN of the event bus
+		BusARN string `yaml:"bus_arn"`
+
+		// The source of the published events, e.g. com.example.zoo
+		EventSource string `yaml:"source"`
+
+		// (Optional) The detail-type of the published events. Defaults to the
+		// name of the mutation publishing the event
+		DetailType string `yaml:"detail_type,omitempty"`
 	}
 
+	// NoneSource represents a NONE data source. Resolvers using it don't call
+	// any other service, so it has no configuration
+	NoneSource struct{}
+
 	unmarshalSource Source
 )
 
-var reSupportedDataSourceTypes = regexp.MustCompile(`(dynamo|aurora)`)
+var (
+	reDynamoProjections  = regexp.MustCompile(`^(ALL|KEYS_ONLY|INCLUDE)$`)
+	reConflictHandlers   = regexp.MustCompile(`^(OPTIMISTIC_CONCURRENCY|AUTOMERGE|LAMBDA)$`)
+	reDynamoBillingModes = regexp.MustCompile(`^(PAY_PER_REQUEST|PROVISIONED)$`)
+	reDynamoStreams      = regexp.MustCompile(`^(KEYS_ONLY|NEW_IMAGE|OLD_IMAGE|NEW_AND_OLD_IMAGES)$`)
+	reDynamoTableClasses = regexp.MustCompile(`^(STANDARD|STANDARD_INFREQUENT_ACCESS)$`)
+)
+
+const (
+	defaultBaseTableTTL      = 43200
+	defaultDeltaSyncTableTTL = 1440
+	defaultTargetUtilization = 70
+)
+
+// supportedDataSourceTypes lists the data source kinds a source may declare
+var supportedDataSourceTypes = []string{"dynamo", "sql", "lambda", "http", "opensearch", "eventbridge", "none"}
 
 // UnmarshalYAML satisfies the custom unmarshaler interface for go-yaml. It is
 // called automatically by the YAML unmarshal.
@@ -69,14 +261,103 @@ func (ds *Source) UnmarshalYAML(unmarshal func(interface{}) error) error {
 				ds.Dynamo.SortKey.Type = "S"
 			}
 		}
+		if err := ds.Dynamo.checkIndexes(ds.Name); err != nil {
+			return err
+		}
+		if err := ds.Dynamo.checkConflict(ds.Name); err != nil {
+			return err
+		}
+		if ds.Dynamo.TTL != nil && ds.Dynamo.TTL.Attribute == "" {
+			return fmt.Errorf("dynamo datasource '%s' ttl does not declare an attribute", ds.Name)
+		}
+		if err := ds.Dynamo.checkTable(ds.Name); err != nil {
+			return err
+		}
 	case ds.SQL != nil:
 		ds.Type = "sql"
+		switch {
+		case ds.SQL.ClusterARN == "":
+			return fmt.Errorf("sql datasource '%s' does not declare a cluster_arn", ds.Name)
+		case ds.SQL.SecretARN == "":
+			return fmt.Errorf("sql datasource '%s' does not declare a secret_arn", ds.Name)
+		case ds.SQL.Database == "":
+			return fmt.Errorf("sql datasource '%s' does not declare a database", ds.Name)
+		}
+		if ds.SQL.Table == "" {
+			ds.SQL.Table = ds.Name
+		}
+	case ds.Lambda != nil:
+		ds.Type = "lambda"
+		switch {
+		case ds.Lambda.FunctionARN == "" && ds.Lambda.Function == "":
+			return fmt.Errorf("lambda datasource '%s' does not declare a function_arn or function", ds.Name)
+		case ds.Lambda.FunctionARN != "" && ds.Lambda.Function != "":
+			return fmt.Errorf("lambda datasource '%s' cannot declare both a function_arn and function", ds.Name)
+		}
+	case ds.HTTP != nil:
+		ds.Type = "http"
+		u, err := url.Parse(ds.HTTP.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.Trim(u.Path, "/") != "" {
+			return fmt.Errorf("http datasource '%s' endpoint '%s' must be a scheme and host only, e.g. https://api.example.com", ds.Name, ds.HTTP.Endpoint)
+		}
+		if iam := ds.HTTP.IAM; iam != nil {
+			if iam.SigningRegion == "" || iam.SigningService == "" {
+				return fmt.Errorf("http datasource '%s' iam config must declare a signing_region and signing_service", ds.Name)
+			}
+			if len(iam.Actions) == 0 && iam.SigningService == "execute-api" {
+				iam.Actions = []string{"execute-api:Invoke"}
+			}
+			switch {
+			case len(iam.Actions) == 0:
+				return fmt.Errorf("http datasource '%s' iam config must declare the actions allowed on service '%s'", ds.Name, iam.SigningService)
+			case len(iam.Resources) == 0:
+				return fmt.Errorf("http datasource '%s' iam config must declare the resources its actions are allowed on", ds.Name)
+			}
+		}
+	case ds.OpenSearch != nil:
+		ds.Type = "opensearch"
+		switch {
+		case !strings.HasPrefix(ds.OpenSearch.Endpoint, "https://"):
+			return fmt.Errorf("opensearch datasource '%s' endpoint must be an https url", ds.Name)
+		case ds.OpenSearch.DomainARN == "":
+			return fmt.Errorf("opensearch datasource '%s' does not declare a domain_arn", ds.Name)
+		}
+		if ds.OpenSearch.Index == "" {
+			ds.OpenSearch.Index = ds.Name
+		}
+	case ds.EventBridge != nil:
+		ds.Type = "eventbridge"
+		switch {
+		case ds.EventBridge.BusARN == "":
+			return fmt.Errorf("eventbridge datasource '%s' does not declare a bus_arn", ds.Name)
+		case ds.EventBridge.EventSource == "":
+			return fmt.Errorf("eventbridge datasource '%s' does not declare the source of its events", ds.Name)
+		}
+	case ds.None != nil:
+		ds.Type = "none"
 	default:
-		return errors.New("must specify a support data source type")
+		return fmt.Errorf("datasource '%s' must declare one of the supported types: %s", ds.Name, strings.Join(supportedDataSourceTypes, ", "))
 	}
 	return nil
 }
 
+// ARN returns the terraform expression for the ARN of the lambda function
+func (l *LambdaSource) ARN() string {
+	if l.Function != "" {
+		return "aws_lambda_function." + l.Function + ".arn"
+	}
+	return `"` + l.FunctionARN + `"`
+}
+
+// PolicyARN returns the ARN of the lambda function for use within a
+// terraform heredoc string
+func (l *LambdaSource) PolicyARN() string {
+	if l.Function != "" {
+		return "${aws_lambda_function." + l.Function + ".arn}"
+	}
+	return l.FunctionARN
+}
+
 // GenerateBytes renders the datasource ready to be written to the output stream
 func (ds *Source) GenerateBytes() ([]byte, error) {
 	generated := bytes.Buffer{}
@@ -96,3 +377,194 @@ func (ds *Source) GenerateBytes() ([]byte, error) {
 func (ds *Source) OutputName() string {
 	return strings.ToLower(fmt.Sprintf("_datasource_%s_%s.tf", ds.Type, ds.Name))
 }
+
+// tableName returns the name of the table of the dynamo source as it's
+// deployed, i.e. prefixed by the environment of the target
+func tableName(source string) string {
+	if Target == TargetCloudFormation {
+		return "${Environment}-" + source
+	}
+	return "${terraform.workspace}-" + source
+}
+
+// checkIndexes sets the defaults of the secondary indexes of the table and
+// checks they're complete
+func (d *DynamoSource) checkIndexes(source string) error {
+	names := map[string]bool{}
+	for _, idx := range d.indexes() {
+		if idx.Name == "" {
+			return fmt.Errorf("dynamo datasource '%s' declares an index without a name", source)
+		}
+		if names[idx.Name] {
+			return fmt.Errorf("dynamo datasource '%s' declares index '%s' more than once", source, idx.Name)
+		}
+		names[idx.Name] = true
+
+		if idx.Projection == "" {
+			idx.Projection = "ALL"
+		}
+		if !reDynamoProjections.MatchString(idx.Projection) {
+			return fmt.Errorf("dynamo datasource '%s' index '%s' projection '%s' must be one of ALL, KEYS_ONLY or INCLUDE", source, idx.Name, idx.Projection)
+		}
+		if (idx.Projection == "INCLUDE") != (len(idx.NonKeyAttributes) > 0) {
+			return fmt.Errorf("dynamo datasource '%s' index '%s' must declare non_key_attributes only when its projection is INCLUDE", source, idx.Name)
+		}
+	}
+
+	for _, idx := range d.GSI {
+		if idx.HashKey == nil {
+			return fmt.Errorf("dynamo datasource '%s' global index '%s' does not declare a hash_key", source, idx.Name)
+		}
+	}
+	for _, idx := range d.LSI {
+		if idx.SortKey == nil {
+			return fmt.Errorf("dynamo datasource '%s' local index '%s' does not declare a sort_key", source, idx.Name)
+		}
+		if idx.HashKey != nil && idx.HashKey.Name != d.HashKey.Name {
+			return fmt.Errorf("dynamo datasource '%s' local index '%s' must use the hash key of the table, '%s'", source, idx.Name, d.HashKey.Name)
+		}
+		idx.HashKey = d.HashKey
+	}
+
+	for _, idx := range d.indexes() {
+		for _, k := range []*DynamoKeyType{idx.HashKey, idx.SortKey} {
+			if k != nil && k.Type == "" {
+				k.Type = "S"
+			}
+		}
+	}
+	return nil
+}
+
+// checkConflict sets the defaults of the conflict detection of the table and
+// checks it's complete
+func (d *DynamoSource) checkConflict(source string) error {
+	c := d.Conflict
+	if c == nil {
+		return nil
+	}
+	switch {
+	case !reConflictHandlers.MatchString(c.Handler):
+		return fmt.Errorf("dynamo datasource '%s' conflict handler '%s' must be one of OPTIMISTIC_CONCURRENCY, AUTOMERGE or LAMBDA", source, c.Handler)
+	case (c.Handler == "LAMBDA") != (c.LambdaARN != ""):
+		return fmt.Errorf("dynamo datasource '%s' must declare a conflict lambda_arn only when its handler is LAMBDA", source)
+	case c.BaseTableTTL < 0 || c.DeltaSyncTableTTL < 0:
+		return fmt.Errorf("dynamo datasource '%s' conflict ttls must be positive", source)
+	}
+	if c.BaseTableTTL == 0 {
+		c.BaseTableTTL = defaultBaseTableTTL
+	}
+	if c.DeltaSyncTableTTL == 0 {
+		c.DeltaSyncTableTTL = defaultDeltaSyncTableTTL
+	}
+	return nil
+}
+
+// checkTable sets the defaults of the settings of the table and checks
+// they're consistent
+func (d *DynamoSource) checkTable(source string) error {
+	switch {
+	case d.BillingMode != "" && !reDynamoBillingModes.MatchString(d.BillingMode):
+		return fmt.Errorf("dynamo datasource '%s' billing_mode '%s' must be one of PAY_PER_REQUEST or PROVISIONED", source, d.BillingMode)
+	case d.Provisioned() && (d.Capacity == nil || d.Capacity.Read <= 0 || d.Capacity.Write <= 0):
+		return fmt.Errorf("dynamo datasource '%s' must declare a positive read and write capacity when its billing_mode is PROVISIONED", source)
+	case !d.Provisioned() && d.Capacity != nil:
+		return fmt.Errorf("dynamo datasource '%s' can only declare a capacity when its billing_mode is PROVISIONED", source)
+	case d.Stream != "" && !reDynamoStreams.MatchString(d.Stream):
+		return fmt.Errorf("dynamo datasource '%s' stream '%s' must be one of KEYS_ONLY, NEW_IMAGE, OLD_IMAGE or NEW_AND_OLD_IMAGES", source, d.Stream)
+	case d.TableClass != "" && !reDynamoTableClasses.MatchString(d.TableClass):
+		return fmt.Errorf("dynamo datasource '%s' table_class '%s' must be one of STANDARD or STANDARD_INFREQUENT_ACCESS", source, d.TableClass)
+	case d.Encryption != nil && d.Encryption.KMSKeyARN != "" && !strings.HasPrefix(d.Encryption.KMSKeyARN, "arn:"):
+		return fmt.Errorf("dynamo datasource '%s' encryption kms_key_arn '%s' must be an ARN", source, d.Encryption.KMSKeyARN)
+	}
+
+	for _, reserved := range []string{"Environment", "Name"} {
+		if _, ok := d.Tags[reserved]; ok {
+			return fmt.Errorf("dynamo datasource '%s' cannot declare the %s tag, which is set automatically", source, reserved)
+		}
+	}
+
+	if d.Capacity == nil || d.Capacity.Autoscaling == nil {
+		return nil
+	}
+	a := d.Capacity.Autoscaling
+	if a.TargetUtilization == 0 {
+		a.TargetUtilization = defaultTargetUtilization
+	}
+	switch {
+	case a.MaxRead < d.Capacity.Read || a.MaxWrite < d.Capacity.Write:
+		return fmt.Errorf("dynamo datasource '%s' autoscaling max_read and max_write must be at least the read and write capacity", source)
+	case a.TargetUtilization < 20 || a.TargetUtilization > 90:
+		return fmt.Errorf("dynamo datasource '%s' autoscaling target_utilization must be from 20 to 90", source)
+	}
+	return nil
+}
+
+// ScalableDimension is the read or write capacity of a table scaled by
+// autoscaling
+type ScalableDimension struct {
+	Dimension string
+	Suffix    string
+	Min       int
+	Max       int
+	Target    int
+}
+
+// ScalableDimensions returns the read and write capacity of the table where
+// it is autoscaled
+func (d *DynamoSource) ScalableDimensions() []ScalableDimension {
+	if d.Capacity == nil || d.Capacity.Autoscaling == nil {
+		return nil
+	}
+	a := d.Capacity.Autoscaling
+	return []ScalableDimension{
+		{Dimension: "Read", Suffix: "read", Min: d.Capacity.Read, Max: a.MaxRead, Target: a.TargetUtilization},
+		{Dimension: "Write", Suffix: "write", Min: d.Capacity.Write, Max: a.MaxWrite, Target: a.TargetUtilization},
+	}
+}
+
+// Provisioned reports whether the capacity of the table is provisioned rather
+// than paid for per request
+func (d *DynamoSource) Provisioned() bool {
+	return d.BillingMode == "PROVISIONED"
+}
+
+// Attributes returns the key attributes of the table and its indexes, each
+// declared once
+func (d *DynamoSource) Attributes() []*DynamoKeyType {
+	keys := []*DynamoKeyType{d.HashKey, d.SortKey}
+	for _, idx := range d.indexes() {
+		keys = append(keys, idx.HashKey, idx.SortKey)
+	}
+
+	attributes := []*DynamoKeyType{}
+	seen := map[string]bool{}
+	for _, k := range keys {
+		if k == nil || seen[k.Name] {
+			continue
+		}
+		seen[k.Name] = true
+		attributes = append(attributes, k)
+	}
+	return attributes
+}
+
+// indexes returns the global then local secondary indexes of the table
+func (d *DynamoSource) indexes() []*DynamoIndex {
+	return append(append([]*DynamoIndex{}, d.GSI...), d.LSI...)
+}
+
+// HasIndexes reports whether the table has any secondary indexes
+func (d *DynamoSource) HasIndexes() bool {
+	return len(d.GSI)+len(d.LSI) > 0
+}
+
+// index returns the named secondary index, or nil if it doesn't exist
+func (d *DynamoSource) index(name string) *DynamoIndex {
+	for _, idx := range d.indexes() {
+		if idx.Name == name {
+			return idx
+		}
+	}
+	return nil
+}
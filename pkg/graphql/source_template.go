@@ -2,9 +2,9 @@ package graphql
 
 var sourceTemplate = `
 {{ if eq .Type "dynamo" -}}
-resource "aws_iam_role_policy" "record_dynamo_{{.Name}}" {
+resource "aws_iam_role_policy" "{{.API.Name}}_dynamo_{{.Name}}" {
 	name		= "${terraform.workspace}-dynamo-{{.Name}}"
-	role 		= aws_iam_role.record.id
+	role 		= aws_iam_role.{{.API.Name}}.id
 	policy 		= <<EOF
 {
   "Version": "2012-10-17",
@@ -15,9 +15,22 @@ resource "aws_iam_role_policy" "record_dynamo_{{.Name}}" {
     ],
     "Effect": "Allow",
     "Resource": [
-      "${aws_dynamodb_table.{{.Name}}.arn}"
+      "${aws_dynamodb_table.{{.Name}}.arn}"{{ if .Dynamo.HasIndexes }},
+      "${aws_dynamodb_table.{{.Name}}.arn}/index/*"{{ end }}{{ if .Dynamo.Conflict }},
+      "${aws_dynamodb_table.{{.Name}}_delta.arn}"{{ end }}
     ]
     }
+    {{- if and .Dynamo.Conflict .Dynamo.Conflict.LambdaARN }},
+    {
+    "Action": [
+      "lambda:InvokeFunction"
+    ],
+    "Effect": "Allow",
+    "Resource": [
+      "{{.Dynamo.Conflict.LambdaARN}}"
+    ]
+    }
+    {{- end }}
   ]
 }
 EOF
@@ -25,48 +38,391 @@ EOF
 
 resource "aws_dynamodb_table" "{{.Name}}" {
 	name 			= "${terraform.workspace}-{{.Name}}"
+	{{- if .Dynamo.Provisioned }}
+	billing_mode 	= "PROVISIONED"
+	read_capacity	= {{.Dynamo.Capacity.Read}}
+	write_capacity	= {{.Dynamo.Capacity.Write}}
+	{{- else }}
 	billing_mode 	= "PAY_PER_REQUEST"
+	{{- end }}
 	hash_key 		= "{{.Dynamo.HashKey.Name}}"
-	{{ if .Dynamo.SortKey -}}
+	{{- if .Dynamo.SortKey }}
 	range_key		= "{{.Dynamo.SortKey.Name}}"
+	{{- end }}
+	{{- if .Dynamo.TableClass }}
+	table_class		= "{{.Dynamo.TableClass}}"
+	{{- end }}
+	{{- if .Dynamo.DeletionProtection }}
+	deletion_protection_enabled = true
+	{{- end }}
+	{{- if .Dynamo.Stream }}
+	stream_enabled		= true
+	stream_view_type	= "{{.Dynamo.Stream}}"
+	{{- end }}
+	{{- range .Dynamo.Attributes }}
 
 	attribute {
-		name = "{{.Dynamo.SortKey.Name}}"
-		type = "{{.Dynamo.SortKey.Type}}"
+		name = "{{.Name}}"
+		type = "{{.Type}}"
+	}
+	{{- end }}
+	{{- range .Dynamo.GSI }}
+
+	global_secondary_index {
+		name			= "{{.Name}}"
+		hash_key		= "{{.HashKey.Name}}"
+		{{- if .SortKey }}
+		range_key		= "{{.SortKey.Name}}"
+		{{- end }}
+		projection_type	= "{{.Projection}}"
+		{{- if .NonKeyAttributes }}
+		non_key_attributes	= [{{ range $i, $a := .NonKeyAttributes }}{{ if $i }}, {{ end }}"{{ $a }}"{{ end }}]
+		{{- end }}
+		{{- if $.Dynamo.Provisioned }}
+		read_capacity	= {{$.Dynamo.Capacity.Read}}
+		write_capacity	= {{$.Dynamo.Capacity.Write}}
+		{{- end }}
 	}
 	{{- end }}
+	{{- range .Dynamo.LSI }}
 
-	{{ if .Dynamo.Backup }}point_in_time_recovery {
+	local_secondary_index {
+		name			= "{{.Name}}"
+		range_key		= "{{.SortKey.Name}}"
+		projection_type	= "{{.Projection}}"
+		{{- if .NonKeyAttributes }}
+		non_key_attributes	= [{{ range $i, $a := .NonKeyAttributes }}{{ if $i }}, {{ end }}"{{ $a }}"{{ end }}]
+		{{- end }}
+	}
+	{{- end }}
+	{{- if .Dynamo.Backup }}
+
+	point_in_time_recovery {
 		enabled = true
-	}{{ end }}
+	}
+	{{- end }}
 
-	attribute {
-		name = "{{.Dynamo.HashKey.Name}}"
-		type = "{{.Dynamo.HashKey.Type}}"
+	{{- if .Dynamo.TTL }}
+
+	ttl {
+		attribute_name = "{{.Dynamo.TTL.Attribute}}"
+		enabled        = true
 	}
+	{{- else }}
 
 	ttl {
 		attribute_name = "" # Has to be empty or terraform won't update properly
 		enabled        = false
 	}
+	{{- end }}
+	{{- with .Dynamo.Encryption }}
+
+	server_side_encryption {
+		enabled     = true
+		{{- if .KMSKeyARN }}
+		kms_key_arn = "{{.KMSKeyARN}}"
+		{{- end }}
+	}
+	{{- end }}
+	{{- if .Dynamo.ScalableDimensions }}
+
+	lifecycle {
+		ignore_changes = [read_capacity, write_capacity]
+	}
+	{{- end }}
 
 	tags = {
 		Environment = terraform.workspace
 		Name        = "{{.Name}}"
+		{{- range $k, $v := .Dynamo.Tags }}
+		{{ printf "%q" $k }} = {{ printf "%q" $v }}
+		{{- end }}
 	}
 }
 
 resource "aws_appsync_datasource" "{{.Name}}" {
-	api_id 				= aws_appsync_graphql_api.record.id
+	api_id 				= aws_appsync_graphql_api.{{.API.Name}}.id
 	name 				= "${terraform.workspace}_{{.Name}}"
-	service_role_arn 	= aws_iam_role.record.arn
+	service_role_arn 	= aws_iam_role.{{.API.Name}}.arn
 	type				= "AMAZON_DYNAMODB"
 	depends_on			= [
 		aws_dynamodb_table.{{.Name}}
 	]
 	dynamodb_config {
 		table_name = aws_dynamodb_table.{{.Name}}.name
+		{{- with .Dynamo.Conflict }}
+		versioned  = true
+
+		delta_sync_config {
+			base_table_ttl        = {{.BaseTableTTL}}
+			delta_sync_table_name = aws_dynamodb_table.{{$.Name}}_delta.name
+			delta_sync_table_ttl  = {{.DeltaSyncTableTTL}}
+		}
+		{{- end }}
+	}
+}
+{{- if .Dynamo.Conflict }}
+
+resource "aws_dynamodb_table" "{{.Name}}_delta" {
+	name 			= "${terraform.workspace}-{{.Name}}-delta"
+	billing_mode 	= "PAY_PER_REQUEST"
+	hash_key 		= "ds_pk"
+	range_key		= "ds_sk"
+
+	attribute {
+		name = "ds_pk"
+		type = "S"
+	}
+
+	attribute {
+		name = "ds_sk"
+		type = "S"
+	}
+
+	ttl {
+		attribute_name = "_ttl"
+		enabled        = true
 	}
+
+	tags = {
+		Environment = terraform.workspace
+		Name        = "{{.Name}}-delta"
+	}
+}
+{{- end }}
+{{- range .Dynamo.ScalableDimensions }}
+
+resource "aws_appautoscaling_target" "{{$.Name}}_{{.Suffix}}" {
+	min_capacity		= {{.Min}}
+	max_capacity		= {{.Max}}
+	resource_id			= "table/${aws_dynamodb_table.{{$.Name}}.name}"
+	scalable_dimension	= "dynamodb:table:{{.Dimension}}CapacityUnits"
+	service_namespace	= "dynamodb"
+}
+
+resource "aws_appautoscaling_policy" "{{$.Name}}_{{.Suffix}}" {
+	name				= "DynamoDB{{.Dimension}}CapacityUtilization:${aws_appautoscaling_target.{{$.Name}}_{{.Suffix}}.resource_id}"
+	policy_type			= "TargetTrackingScaling"
+	resource_id			= aws_appautoscaling_target.{{$.Name}}_{{.Suffix}}.resource_id
+	scalable_dimension	= aws_appautoscaling_target.{{$.Name}}_{{.Suffix}}.scalable_dimension
+	service_namespace	= aws_appautoscaling_target.{{$.Name}}_{{.Suffix}}.service_namespace
+
+	target_tracking_scaling_policy_configuration {
+		predefined_metric_specification {
+			predefined_metric_type = "DynamoDB{{.Dimension}}CapacityUtilization"
+		}
+		target_value = {{.Target}}
+	}
+}
+{{- end }}
+{{- end }}
+{{- if eq .Type "sql" -}}
+resource "aws_iam_role_policy" "{{.API.Name}}_sql_{{.Name}}" {
+	name		= "${terraform.workspace}-sql-{{.Name}}"
+	role 		= aws_iam_role.{{.API.Name}}.id
+	policy 		= <<EOF
+{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+    "Action": [
+      "rds-data:BatchExecuteStatement",
+      "rds-data:BeginTransaction",
+      "rds-data:CommitTransaction",
+      "rds-data:ExecuteStatement",
+      "rds-data:RollbackTransaction"
+    ],
+    "Effect": "Allow",
+    "Resource": [
+      "{{.SQL.ClusterARN}}",
+      "{{.SQL.ClusterARN}}:*"
+    ]
+    },
+    {
+    "Action": [
+      "secretsmanager:GetSecretValue"
+    ],
+    "Effect": "Allow",
+    "Resource": [
+      "{{.SQL.SecretARN}}",
+      "{{.SQL.SecretARN}}:*"
+    ]
+    }
+  ]
+}
+EOF
+  }
+
+resource "aws_appsync_datasource" "{{.Name}}" {
+	api_id 				= aws_appsync_graphql_api.{{.API.Name}}.id
+	name 				= "${terraform.workspace}_{{.Name}}"
+	service_role_arn 	= aws_iam_role.{{.API.Name}}.arn
+	type				= "RELATIONAL_DATABASE"
+	relational_database_config {
+		http_endpoint_config {
+			db_cluster_identifier	= "{{.SQL.ClusterARN}}"
+			aws_secret_store_arn	= "{{.SQL.SecretARN}}"
+			database_name			= "{{.SQL.Database}}"
+		}
+	}
+}
+{{- end }}
+{{- if eq .Type "lambda" -}}
+resource "aws_iam_role_policy" "{{.API.Name}}_lambda_{{.Name}}" {
+	name		= "${terraform.workspace}-lambda-{{.Name}}"
+	role 		= aws_iam_role.{{.API.Name}}.id
+	policy 		= <<EOF
+{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+    "Action": [
+      "lambda:InvokeFunction"
+    ],
+    "Effect": "Allow",
+    "Resource": [
+      "{{.Lambda.PolicyARN}}",
+      "{{.Lambda.PolicyARN}}:*"
+    ]
+    }
+  ]
+}
+EOF
+}
+
+resource "aws_appsync_datasource" "{{.Name}}" {
+	api_id 				= aws_appsync_graphql_api.{{.API.Name}}.id
+	name 				= "${terraform.workspace}_{{.Name}}"
+	service_role_arn 	= aws_iam_role.{{.API.Name}}.arn
+	type				= "AWS_LAMBDA"
+	lambda_config {
+		function_arn	= {{.Lambda.ARN}}
+	}
+}
+{{- end }}
+{{- if eq .Type "http" -}}
+{{- with .HTTP.IAM -}}
+resource "aws_iam_role_policy" "{{$.API.Name}}_http_{{$.Name}}" {
+	name		= "${terraform.workspace}-http-{{$.Name}}"
+	role 		= aws_iam_role.{{$.API.Name}}.id
+	policy 		= <<EOF
+{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+    "Action": [
+      {{- range $i, $a := .Actions }}{{ if $i }},{{ end }}
+      "{{ $a }}"
+      {{- end }}
+    ],
+    "Effect": "Allow",
+    "Resource": [
+      {{- range $i, $r := .Resources }}{{ if $i }},{{ end }}
+      "{{ $r }}"
+      {{- end }}
+    ]
+    }
+  ]
+}
+EOF
+}
+
+{{ end -}}
+resource "aws_appsync_datasource" "{{.Name}}" {
+	api_id 				= aws_appsync_graphql_api.{{.API.Name}}.id
+	name 				= "${terraform.workspace}_{{.Name}}"
+	{{- if .HTTP.IAM }}
+	service_role_arn 	= aws_iam_role.{{.API.Name}}.arn
+	{{- end }}
+	type				= "HTTP"
+	http_config {
+		endpoint	= "{{.HTTP.Endpoint}}"
+		{{- if .HTTP.IAM }}
+		authorization_config {
+			authorization_type	= "AWS_IAM"
+			aws_iam_config {
+				signing_region			= "{{.HTTP.IAM.SigningRegion}}"
+				signing_service_name	= "{{.HTTP.IAM.SigningService}}"
+			}
+		}
+		{{- end }}
+	}
+}
+{{- end }}
+{{- if eq .Type "opensearch" -}}
+resource "aws_iam_role_policy" "{{.API.Name}}_opensearch_{{.Name}}" {
+	name		= "${terraform.workspace}-opensearch-{{.Name}}"
+	role 		= aws_iam_role.{{.API.Name}}.id
+	policy 		= <<EOF
+{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+    "Action": [
+      "es:ESHttpGet",
+      "es:ESHttpPost"
+    ],
+    "Effect": "Allow",
+    "Resource": [
+      "{{.OpenSearch.DomainARN}}",
+      "{{.OpenSearch.DomainARN}}/*"
+    ]
+    }
+  ]
+}
+EOF
+}
+
+resource "aws_appsync_datasource" "{{.Name}}" {
+	api_id 				= aws_appsync_graphql_api.{{.API.Name}}.id
+	name 				= "${terraform.workspace}_{{.Name}}"
+	service_role_arn 	= aws_iam_role.{{.API.Name}}.arn
+	type				= "AMAZON_OPENSEARCH_SERVICE"
+	opensearchservice_config {
+		endpoint	= "{{.OpenSearch.Endpoint}}"
+		{{- if .OpenSearch.Region }}
+		region		= "{{.OpenSearch.Region}}"
+		{{- end }}
+	}
+}
+{{- end }}
+{{- if eq .Type "eventbridge" -}}
+resource "aws_iam_role_policy" "{{.API.Name}}_eventbridge_{{.Name}}" {
+	name		= "${terraform.workspace}-eventbridge-{{.Name}}"
+	role 		= aws_iam_role.{{.API.Name}}.id
+	policy 		= <<EOF
+{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+    "Action": [
+      "events:PutEvents"
+    ],
+    "Effect": "Allow",
+    "Resource": [
+      "{{.EventBridge.BusARN}}"
+    ]
+    }
+  ]
+}
+EOF
+}
+
+resource "aws_appsync_datasource" "{{.Name}}" {
+	api_id 				= aws_appsync_graphql_api.{{.API.Name}}.id
+	name 				= "${terraform.workspace}_{{.Name}}"
+	service_role_arn 	= aws_iam_role.{{.API.Name}}.arn
+	type				= "AMAZON_EVENTBRIDGE"
+	event_bridge_config {
+		event_bus_arn	= "{{.EventBridge.BusARN}}"
+	}
+}
+{{- end }}
+{{- if eq .Type "none" -}}
+resource "aws_appsync_datasource" "{{.Name}}" {
+	api_id 				= aws_appsync_graphql_api.{{.API.Name}}.id
+	name 				= "${terraform.workspace}_{{.Name}}"
+	type				= "NONE"
 }
 {{- end }}
 `
@@ -2,6 +2,7 @@ package graphql_test
 
 import (
 	"errors"
+	"strings"
 	"testing"
 
 	"github.com/ONSdigital/aws-appsync-generator/pkg/graphql"
@@ -33,22 +34,373 @@ func TestUnmarshalDataSource(t *testing.T) {
 		},
 		{
 			"Good aurora data source",
-			[]byte("name: aurorasource\nsql:\n  primary_key: key"),
+			[]byte("name: aurorasource\nsql:\n  primary_key: key\n  cluster_arn: cluster\n  secret_arn: secret\n  database: db"),
 			&graphql.Source{
 				Name: "aurorasource",
 				Type: "sql",
 				SQL: &graphql.SQLSource{
 					PrimaryKey: "key",
+					ClusterARN: "cluster",
+					SecretARN:  "secret",
+					Database:   "db",
+					Table:      "aurorasource",
 				},
 			},
 			nil,
 		},
-		// {
-		// 	"Unsupported type",
-		// 	[]byte("name: unsupported\ntype: sheepdb"),
-		// 	nil,
-		// 	errors.New("datasource 'unsupported' has unsupported type: sheepdb"),
-		// },
+		{
+			"Aurora data source with table",
+			[]byte("name: aurorasource\nsql:\n  cluster_arn: cluster\n  secret_arn: secret\n  database: db\n  table: people"),
+			&graphql.Source{
+				Name: "aurorasource",
+				Type: "sql",
+				SQL: &graphql.SQLSource{
+					ClusterARN: "cluster",
+					SecretARN:  "secret",
+					Database:   "db",
+					Table:      "people",
+				},
+			},
+			nil,
+		},
+		{
+			"Aurora data source missing cluster",
+			[]byte("name: aurorasource\nsql:\n  secret_arn: secret\n  database: db"),
+			nil,
+			errors.New("sql datasource 'aurorasource' does not declare a cluster_arn"),
+		},
+		{
+			"Lambda data source by arn",
+			[]byte("name: lambdasource\nlambda:\n  function_arn: arn:aws:lambda:eu-west-2:123456789012:function:weigh"),
+			&graphql.Source{
+				Name: "lambdasource",
+				Type: "lambda",
+				Lambda: &graphql.LambdaSource{
+					FunctionARN: "arn:aws:lambda:eu-west-2:123456789012:function:weigh",
+				},
+			},
+			nil,
+		},
+		{
+			"Lambda data source by terraform reference",
+			[]byte("name: lambdasource\nlambda:\n  function: weigh"),
+			&graphql.Source{
+				Name: "lambdasource",
+				Type: "lambda",
+				Lambda: &graphql.LambdaSource{
+					Function: "weigh",
+				},
+			},
+			nil,
+		},
+		{
+			"Lambda data source missing function",
+			[]byte("name: lambdasource\nlambda: {}"),
+			nil,
+			errors.New("lambda datasource 'lambdasource' does not declare a function_arn or function"),
+		},
+		{
+			"Lambda data source with both arn and reference",
+			[]byte("name: lambdasource\nlambda:\n  function: weigh\n  function_arn: arn"),
+			nil,
+			errors.New("lambda datasource 'lambdasource' cannot declare both a function_arn and function"),
+		},
+		{
+			"Http data source with signing",
+			[]byte("name: httpsource\nhttp:\n  endpoint: https://api.example.com\n  iam:\n    signing_region: eu-west-2\n    signing_service: execute-api\n    resources: [arn:aws:execute-api:eu-west-2:123456789012:abc123/*]"),
+			&graphql.Source{
+				Name: "httpsource",
+				Type: "http",
+				HTTP: &graphql.HTTPSource{
+					Endpoint: "https://api.example.com",
+					IAM: &graphql.HTTPIAMConfig{
+						SigningRegion:  "eu-west-2",
+						SigningService: "execute-api",
+						Actions:        []string{"execute-api:Invoke"},
+						Resources:      []string{"arn:aws:execute-api:eu-west-2:123456789012:abc123/*"},
+					},
+				},
+			},
+			nil,
+		},
+		{
+			"Http data source signing for another service",
+			[]byte("name: httpsource\nhttp:\n  endpoint: https://lambda.eu-west-2.amazonaws.com\n  iam:\n    signing_region: eu-west-2\n    signing_service: lambda\n    actions: [lambda:InvokeFunction]\n    resources: [arn:aws:lambda:eu-west-2:123456789012:function:weigh]"),
+			&graphql.Source{
+				Name: "httpsource",
+				Type: "http",
+				HTTP: &graphql.HTTPSource{
+					Endpoint: "https://lambda.eu-west-2.amazonaws.com",
+					IAM: &graphql.HTTPIAMConfig{
+						SigningRegion:  "eu-west-2",
+						SigningService: "lambda",
+						Actions:        []string{"lambda:InvokeFunction"},
+						Resources:      []string{"arn:aws:lambda:eu-west-2:123456789012:function:weigh"},
+					},
+				},
+			},
+			nil,
+		},
+		{
+			"Http data source signing for another service without actions",
+			[]byte("name: httpsource\nhttp:\n  endpoint: https://lambda.eu-west-2.amazonaws.com\n  iam:\n    signing_region: eu-west-2\n    signing_service: lambda\n    resources: [arn:aws:lambda:eu-west-2:123456789012:function:weigh]"),
+			nil,
+			errors.New("http datasource 'httpsource' iam config must declare the actions allowed on service 'lambda'"),
+		},
+		{
+			"Http data source signing without resources",
+			[]byte("name: httpsource\nhttp:\n  endpoint: https://api.example.com\n  iam:\n    signing_region: eu-west-2\n    signing_service: execute-api"),
+			nil,
+			errors.New("http datasource 'httpsource' iam config must declare the resources its actions are allowed on"),
+		},
+		{
+			"Http data source endpoint with a path",
+			[]byte("name: httpsource\nhttp:\n  endpoint: https://api.example.com/animals"),
+			nil,
+			errors.New("http datasource 'httpsource' endpoint 'https://api.example.com/animals' must be a scheme and host only, e.g. https://api.example.com"),
+		},
+		{
+			"Http data source with incomplete signing",
+			[]byte("name: httpsource\nhttp:\n  endpoint: https://api.example.com\n  iam:\n    signing_region: eu-west-2"),
+			nil,
+			errors.New("http datasource 'httpsource' iam config must declare a signing_region and signing_service"),
+		},
+		{
+			"Opensearch data source defaults the index",
+			[]byte("name: animals\nopensearch:\n  endpoint: https://search-zoo.eu-west-2.es.amazonaws.com\n  domain_arn: arn:aws:es:eu-west-2:123456789012:domain/zoo"),
+			&graphql.Source{
+				Name: "animals",
+				Type: "opensearch",
+				OpenSearch: &graphql.OpenSearchSource{
+					Endpoint:  "https://search-zoo.eu-west-2.es.amazonaws.com",
+					DomainARN: "arn:aws:es:eu-west-2:123456789012:domain/zoo",
+					Index:     "animals",
+				},
+			},
+			nil,
+		},
+		{
+			"Opensearch data source without https",
+			[]byte("name: animals\nopensearch:\n  endpoint: search-zoo.eu-west-2.es.amazonaws.com\n  domain_arn: arn"),
+			nil,
+			errors.New("opensearch datasource 'animals' endpoint must be an https url"),
+		},
+		{
+			"Opensearch data source without a domain",
+			[]byte("name: animals\nopensearch:\n  endpoint: https://search-zoo.eu-west-2.es.amazonaws.com"),
+			nil,
+			errors.New("opensearch datasource 'animals' does not declare a domain_arn"),
+		},
+		{
+			"Eventbridge data source",
+			[]byte("name: events\neventbridge:\n  bus_arn: arn:aws:events:eu-west-2:123456789012:event-bus/zoo\n  source: com.example.zoo"),
+			&graphql.Source{
+				Name: "events",
+				Type: "eventbridge",
+				EventBridge: &graphql.EventBridgeSource{
+					BusARN:      "arn:aws:events:eu-west-2:123456789012:event-bus/zoo",
+					EventSource: "com.example.zoo",
+				},
+			},
+			nil,
+		},
+		{
+			"Eventbridge data source without a bus",
+			[]byte("name: events\neventbridge:\n  source: com.example.zoo"),
+			nil,
+			errors.New("eventbridge datasource 'events' does not declare a bus_arn"),
+		},
+		{
+			"Eventbridge data source without an event source",
+			[]byte("name: events\neventbridge:\n  bus_arn: arn:aws:events:eu-west-2:123456789012:event-bus/zoo"),
+			nil,
+			errors.New("eventbridge datasource 'events' does not declare the source of its events"),
+		},
+		{
+			"None data source",
+			[]byte("name: local\nnone: {}"),
+			&graphql.Source{
+				Name: "local",
+				Type: "none",
+				None: &graphql.NoneSource{},
+			},
+			nil,
+		},
+		{
+			"Dynamo data source with indexes",
+			[]byte("name: animals\ndynamo:\n  hash_key:\n    name: zooId\n  gsi:\n    - name: bySpecies\n      hash_key:\n        name: species\n  lsi:\n    - name: byName\n      sort_key:\n        name: name"),
+			&graphql.Source{
+				Name: "animals",
+				Type: "dynamo",
+				Dynamo: &graphql.DynamoSource{
+					HashKey: &graphql.DynamoKeyType{Name: "zooId", Type: "S"},
+					GSI: []*graphql.DynamoIndex{
+						{Name: "bySpecies", HashKey: &graphql.DynamoKeyType{Name: "species", Type: "S"}, Projection: "ALL"},
+					},
+					LSI: []*graphql.DynamoIndex{
+						{Name: "byName", HashKey: &graphql.DynamoKeyType{Name: "zooId", Type: "S"}, SortKey: &graphql.DynamoKeyType{Name: "name", Type: "S"}, Projection: "ALL"},
+					},
+				},
+			},
+			nil,
+		},
+		{
+			"Dynamo global index without a hash key",
+			[]byte("name: animals\ndynamo:\n  hash_key:\n    name: zooId\n  gsi:\n    - name: bySpecies\n      sort_key:\n        name: species"),
+			nil,
+			errors.New("dynamo datasource 'animals' global index 'bySpecies' does not declare a hash_key"),
+		},
+		{
+			"Dynamo local index with another hash key",
+			[]byte("name: animals\ndynamo:\n  hash_key:\n    name: zooId\n  lsi:\n    - name: byName\n      hash_key:\n        name: species\n      sort_key:\n        name: name"),
+			nil,
+			errors.New("dynamo datasource 'animals' local index 'byName' must use the hash key of the table, 'zooId'"),
+		},
+		{
+			"Dynamo index with a bad projection",
+			[]byte("name: animals\ndynamo:\n  hash_key:\n    name: zooId\n  gsi:\n    - name: bySpecies\n      hash_key:\n        name: species\n      projection: SOME"),
+			nil,
+			errors.New("dynamo datasource 'animals' index 'bySpecies' projection 'SOME' must be one of ALL, KEYS_ONLY or INCLUDE"),
+		},
+		{
+			"Dynamo index including no attributes",
+			[]byte("name: animals\ndynamo:\n  hash_key:\n    name: zooId\n  gsi:\n    - name: bySpecies\n      hash_key:\n        name: species\n      projection: INCLUDE"),
+			nil,
+			errors.New("dynamo datasource 'animals' index 'bySpecies' must declare non_key_attributes only when its projection is INCLUDE"),
+		},
+		{
+			"Dynamo indexes with the same name",
+			[]byte("name: animals\ndynamo:\n  hash_key:\n    name: zooId\n  gsi:\n    - name: byName\n      hash_key:\n        name: species\n  lsi:\n    - name: byName\n      sort_key:\n        name: name"),
+			nil,
+			errors.New("dynamo datasource 'animals' declares index 'byName' more than once"),
+		},
+		{
+			"Dynamo data source with conflict detection",
+			[]byte("name: animals\ndynamo:\n  hash_key:\n    name: id\n  conflict:\n    handler: AUTOMERGE"),
+			&graphql.Source{
+				Name: "animals",
+				Type: "dynamo",
+				Dynamo: &graphql.DynamoSource{
+					HashKey:  &graphql.DynamoKeyType{Name: "id", Type: "S"},
+					Conflict: &graphql.DynamoConflict{Handler: "AUTOMERGE", BaseTableTTL: 43200, DeltaSyncTableTTL: 1440},
+				},
+			},
+			nil,
+		},
+		{
+			"Dynamo conflict with a bad handler",
+			[]byte("name: animals\ndynamo:\n  hash_key:\n    name: id\n  conflict:\n    handler: LAST_WRITER_WINS"),
+			nil,
+			errors.New("dynamo datasource 'animals' conflict handler 'LAST_WRITER_WINS' must be one of OPTIMISTIC_CONCURRENCY, AUTOMERGE or LAMBDA"),
+		},
+		{
+			"Dynamo lambda conflict handler without a function",
+			[]byte("name: animals\ndynamo:\n  hash_key:\n    name: id\n  conflict:\n    handler: LAMBDA"),
+			nil,
+			errors.New("dynamo datasource 'animals' must declare a conflict lambda_arn only when its handler is LAMBDA"),
+		},
+		{
+			"Dynamo data source with ttl",
+			[]byte("name: sessions\ndynamo:\n  hash_key:\n    name: id\n  ttl:\n    attribute: expires"),
+			&graphql.Source{
+				Name: "sessions",
+				Type: "dynamo",
+				Dynamo: &graphql.DynamoSource{
+					HashKey: &graphql.DynamoKeyType{Name: "id", Type: "S"},
+					TTL:     &graphql.DynamoTTL{Attribute: "expires"},
+				},
+			},
+			nil,
+		},
+		{
+			"Dynamo ttl without an attribute",
+			[]byte("name: sessions\ndynamo:\n  hash_key:\n    name: id\n  ttl: {}"),
+			nil,
+			errors.New("dynamo datasource 'sessions' ttl does not declare an attribute"),
+		},
+		{
+			"Dynamo table settings",
+			[]byte("name: animals\ndynamo:\n  hash_key:\n    name: id\n  billing_mode: PROVISIONED\n  capacity:\n    read: 5\n    write: 2\n    autoscaling:\n      max_read: 50\n      max_write: 20\n  encryption: {}\n  stream: NEW_IMAGE\n  table_class: STANDARD_INFREQUENT_ACCESS\n  deletion_protection: true\n  tags:\n    team: zoo"),
+			&graphql.Source{
+				Name: "animals",
+				Type: "dynamo",
+				Dynamo: &graphql.DynamoSource{
+					HashKey:     &graphql.DynamoKeyType{Name: "id", Type: "S"},
+					BillingMode: "PROVISIONED",
+					Capacity: &graphql.DynamoCapacity{
+						Read:        5,
+						Write:       2,
+						Autoscaling: &graphql.DynamoAutoscaling{MaxRead: 50, MaxWrite: 20, TargetUtilization: 70},
+					},
+					Encryption:         &graphql.DynamoEncryption{},
+					Stream:             "NEW_IMAGE",
+					TableClass:         "STANDARD_INFREQUENT_ACCESS",
+					DeletionProtection: true,
+					Tags:               map[string]string{"team": "zoo"},
+				},
+			},
+			nil,
+		},
+		{
+			"Dynamo unknown billing mode",
+			[]byte("name: animals\ndynamo:\n  hash_key:\n    name: id\n  billing_mode: ON_DEMAND"),
+			nil,
+			errors.New("dynamo datasource 'animals' billing_mode 'ON_DEMAND' must be one of PAY_PER_REQUEST or PROVISIONED"),
+		},
+		{
+			"Dynamo provisioned without capacity",
+			[]byte("name: animals\ndynamo:\n  hash_key:\n    name: id\n  billing_mode: PROVISIONED\n  capacity:\n    read: 5"),
+			nil,
+			errors.New("dynamo datasource 'animals' must declare a positive read and write capacity when its billing_mode is PROVISIONED"),
+		},
+		{
+			"Dynamo capacity paid per request",
+			[]byte("name: animals\ndynamo:\n  hash_key:\n    name: id\n  capacity:\n    read: 5\n    write: 5"),
+			nil,
+			errors.New("dynamo datasource 'animals' can only declare a capacity when its billing_mode is PROVISIONED"),
+		},
+		{
+			"Dynamo autoscaling below capacity",
+			[]byte("name: animals\ndynamo:\n  hash_key:\n    name: id\n  billing_mode: PROVISIONED\n  capacity:\n    read: 5\n    write: 5\n    autoscaling:\n      max_read: 50\n      max_write: 1"),
+			nil,
+			errors.New("dynamo datasource 'animals' autoscaling max_read and max_write must be at least the read and write capacity"),
+		},
+		{
+			"Dynamo autoscaling target out of range",
+			[]byte("name: animals\ndynamo:\n  hash_key:\n    name: id\n  billing_mode: PROVISIONED\n  capacity:\n    read: 5\n    write: 5\n    autoscaling:\n      max_read: 50\n      max_write: 50\n      target_utilization: 95"),
+			nil,
+			errors.New("dynamo datasource 'animals' autoscaling target_utilization must be from 20 to 90"),
+		},
+		{
+			"Dynamo unknown stream",
+			[]byte("name: animals\ndynamo:\n  hash_key:\n    name: id\n  stream: ALL_IMAGES"),
+			nil,
+			errors.New("dynamo datasource 'animals' stream 'ALL_IMAGES' must be one of KEYS_ONLY, NEW_IMAGE, OLD_IMAGE or NEW_AND_OLD_IMAGES"),
+		},
+		{
+			"Dynamo unknown table class",
+			[]byte("name: animals\ndynamo:\n  hash_key:\n    name: id\n  table_class: GLACIER"),
+			nil,
+			errors.New("dynamo datasource 'animals' table_class 'GLACIER' must be one of STANDARD or STANDARD_INFREQUENT_ACCESS"),
+		},
+		{
+			"Dynamo encryption key that isn't an ARN",
+			[]byte("name: animals\ndynamo:\n  hash_key:\n    name: id\n  encryption:\n    kms_key_arn: my-key"),
+			nil,
+			errors.New("dynamo datasource 'animals' encryption kms_key_arn 'my-key' must be an ARN"),
+		},
+		{
+			"Dynamo reserved tag",
+			[]byte("name: animals\ndynamo:\n  hash_key:\n    name: id\n  tags:\n    Name: zoo"),
+			nil,
+			errors.New("dynamo datasource 'animals' cannot declare the Name tag, which is set automatically"),
+		},
+		{
+			"Unsupported type",
+			[]byte("name: unsupported\ntype: sheepdb"),
+			nil,
+			errors.New("datasource 'unsupported' must declare one of the supported types: dynamo, sql, lambda, http, opensearch, eventbridge, none"),
+		},
 		{
 			"Missing name",
 			[]byte("type: dynamo"),
@@ -67,3 +419,535 @@ func TestUnmarshalDataSource(t *testing.T) {
 		}
 	}
 }
+
+func TestGenerateSources(t *testing.T) {
+	for _, c := range []struct {
+		scenario   string
+		manifest   []byte
+		resource   string
+		properties map[string]interface{}
+		file       string
+		terraform  []string
+	}{
+		{
+			"OpenSearch",
+			searchManifest,
+			"AnimalsSearchDataSource",
+			map[string]interface{}{
+				"Type": "AMAZON_OPENSEARCH_SERVICE",
+				"OpenSearchServiceConfig": map[string]interface{}{
+					"Endpoint":  "https://search-zoo.eu-west-2.es.amazonaws.com",
+					"AwsRegion": "AWS::Region",
+				},
+			},
+			"_datasource_opensearch_animals_search.tf",
+			[]string{
+				`type = "AMAZON_OPENSEARCH_SERVICE"`,
+				`endpoint = "https://search-zoo.eu-west-2.es.amazonaws.com"`,
+				`"arn:aws:es:eu-west-2:123456789012:domain/zoo/*"`,
+			},
+		},
+		{
+			"EventBridge",
+			eventBridgeManifest,
+			"ZooEventsDataSource",
+			map[string]interface{}{
+				"Type":              "AMAZON_EVENTBRIDGE",
+				"EventBridgeConfig": map[string]interface{}{"EventBusArn": "arn:aws:events:eu-west-2:123456789012:event-bus/zoo"},
+			},
+			"_datasource_eventbridge_zoo_events.tf",
+			[]string{
+				`type = "AMAZON_EVENTBRIDGE"`,
+				`event_bus_arn = "arn:aws:events:eu-west-2:123456789012:event-bus/zoo"`,
+				`"events:PutEvents"`,
+			},
+		},
+		{
+			"None",
+			eventBridgeManifest,
+			"LocalDataSource",
+			map[string]interface{}{"Type": "NONE"},
+			"_datasource_none_local.tf",
+			[]string{`type = "NONE"`},
+		},
+		{
+			"Dynamo detecting conflicts",
+			versionedManifest,
+			"KeepersDataSource",
+			map[string]interface{}{
+				"DynamoDBConfig": map[string]interface{}{
+					"TableName": "KeepersTable",
+					"AwsRegion": "AWS::Region",
+					"Versioned": true,
+					"DeltaSyncConfig": map[string]interface{}{
+						"BaseTableTTL":       "43200",
+						"DeltaSyncTableName": "KeepersDeltaTable",
+						"DeltaSyncTableTTL":  "1440",
+					},
+				},
+			},
+			"_datasource_dynamo_keepers.tf",
+			[]string{
+				`"${aws_dynamodb_table.keepers_delta.arn}"`,
+				"versioned = true",
+				"delta_sync_table_name = aws_dynamodb_table.keepers_delta.name",
+			},
+		},
+		{
+			"Dynamo delta sync table",
+			versionedManifest,
+			"KeepersDeltaTable",
+			map[string]interface{}{"TableName": "${Environment}-keepers-delta"},
+			"_datasource_dynamo_keepers.tf",
+			[]string{`resource "aws_dynamodb_table" "keepers_delta" {`},
+		},
+		{
+			"Dynamo without conflict detection",
+			versionedManifest,
+			"AnimalsDataSource",
+			map[string]interface{}{
+				"DynamoDBConfig": map[string]interface{}{
+					"TableName": "AnimalsTable",
+					"AwsRegion": "AWS::Region",
+				},
+			},
+			"_datasource_dynamo_animals.tf",
+			nil,
+		},
+		{
+			"Dynamo ttl",
+			ttlManifest,
+			"SessionsTable",
+			map[string]interface{}{"TimeToLiveSpecification": map[string]interface{}{"AttributeName": "ttl", "Enabled": true}},
+			"_datasource_dynamo_sessions.tf",
+			[]string{`ttl { attribute_name = "ttl" enabled = true }`},
+		},
+		{
+			"Dynamo ttl on an object field",
+			ttlManifest,
+			"TokensTable",
+			map[string]interface{}{"TimeToLiveSpecification": map[string]interface{}{"AttributeName": "expiresAt", "Enabled": true}},
+			"_datasource_dynamo_tokens.tf",
+			[]string{`ttl { attribute_name = "expiresAt" enabled = true }`},
+		},
+		{
+			"Signed http",
+			httpManifest,
+			"AnimalsPolicy",
+			map[string]interface{}{
+				"PolicyDocument": map[string]interface{}{
+					"Version": "2012-10-17",
+					"Statement": []interface{}{
+						map[string]interface{}{
+							"Effect":   "Allow",
+							"Action":   []interface{}{"execute-api:Invoke"},
+							"Resource": []interface{}{"arn:aws:execute-api:eu-west-2:123456789012:abc123/*"},
+						},
+					},
+				},
+			},
+			"_datasource_http_animals.tf",
+			[]string{
+				`resource "aws_iam_role_policy" "record_http_animals" {`,
+				`"Action": [ "execute-api:Invoke" ], "Effect": "Allow", "Resource": [ "arn:aws:execute-api:eu-west-2:123456789012:abc123/*" ]`,
+				"service_role_arn = aws_iam_role.record.arn",
+			},
+		},
+	} {
+		resource, ok := mustGenerateCloudFormation(t, c.manifest)[c.resource]
+		if !ok {
+			t.Errorf("%s: no resource %s generated", c.scenario, c.resource)
+		}
+		for name, value := range c.properties {
+			assert.Equal(t, value, resource.Properties[name], "%s: %s", c.scenario, name)
+		}
+
+		file := collapse(string(mustGenerate(t, c.manifest)[c.file]))
+		for _, e := range c.terraform {
+			assert.Contains(t, file, collapse(e), c.scenario)
+		}
+	}
+}
+
+func TestGenerateLambdaSource(t *testing.T) {
+	files, err := mustCompileSchema(t, lambdaManifest).Generate()
+	if err != nil {
+		t.Fatalf("error generating schema '%v', expected nil", err)
+	}
+
+	vets := string(files["_datasource_lambda_vets.tf"])
+	assert.Contains(t, vets, `type				= "AWS_LAMBDA"`)
+	assert.Contains(t, vets, "function_arn	= aws_lambda_function.vet_records.arn")
+	assert.Contains(t, vets, `"lambda:InvokeFunction"`)
+	assert.Contains(t, vets, `"${aws_lambda_function.vet_records.arn}:*"`)
+
+	weigh := string(files["_datasource_lambda_weigh.tf"])
+	assert.Contains(t, weigh, `function_arn	= "arn:aws:lambda:eu-west-2:123456789012:function:weigh"`)
+
+	checkups := string(files["_animal_checkups.tf"])
+	assert.Contains(t, checkups, "max_batch_size    = 10")
+	assert.Contains(t, checkups, `"operation": "BatchInvoke"`)
+	assert.Contains(t, checkups, `"selectionSetList": $util.toJson($ctx.info.selectionSetList)`)
+
+	weighAnimal := string(files["_mutation_weighanimal.tf"])
+	assert.NotContains(t, weighAnimal, "max_batch_size")
+	assert.Contains(t, weighAnimal, `"operation": "Invoke"`)
+	assert.Contains(t, weighAnimal, `"identity": $util.toJson($ctx.identity)`)
+}
+
+var lambdaManifest = []byte(`
+sources:
+  default:
+    name: animals
+    dynamo:
+      hash_key:
+        name: id
+  vets:
+    name: vets
+    lambda:
+      function: vet_records
+  weigh:
+    name: weigh
+    lambda:
+      function_arn: arn:aws:lambda:eu-west-2:123456789012:function:weigh
+
+objects:
+  - name: Animal
+    fields:
+      - name: id
+        type: ID!
+      - name: checkups
+        resolver:
+          action: batch-invoke
+          type: [Checkup]
+          source: vets
+  - name: Checkup
+    fields:
+      - name: date
+        type: AWSDate
+
+mutations:
+  - name: weighAnimal
+    resolver:
+      action: invoke
+      type: Animal
+      source: weigh
+      keyFields:
+        - name: id
+          type: ID
+`)
+
+func TestGenerateDynamoIndexes(t *testing.T) {
+	files, err := mustCompileSchema(t, dynamoIndexManifest).Generate()
+	if err != nil {
+		t.Fatalf("error generating schema '%v', expected nil", err)
+	}
+
+	source := string(files["_datasource_dynamo_animals.tf"])
+	assert.Contains(t, source, "\"${aws_dynamodb_table.animals.arn}/index/*\"")
+	assert.Contains(t, source, "attribute {\n\t\tname = \"born\"\n\t\ttype = \"N\"\n\t}")
+	assert.Equal(t, 1, strings.Count(source, "name = \"species\""))
+	assert.Contains(t, source, "global_secondary_index {\n\t\tname\t\t\t= \"bySpecies\"\n\t\thash_key\t\t= \"species\"\n\t\trange_key\t\t= \"born\"\n\t\tprojection_type\t= \"ALL\"\n\t}")
+	assert.Contains(t, source, "non_key_attributes\t= [\"name\"]")
+	assert.Contains(t, source, "local_secondary_index {\n\t\tname\t\t\t= \"byName\"\n\t\trange_key\t\t= \"name\"\n\t\tprojection_type\t= \"KEYS_ONLY\"\n\t}")
+
+	getItems := string(files["_query_animalsbyspecies.tf"])
+	assert.Contains(t, getItems, `"index": "bySpecies",`)
+	assert.Contains(t, getItems, `#set( $keys = ["species","born"] )`)
+	assert.Contains(t, getItems, `#set( $expression = "$expression#$key = :$key" )`)
+	assert.Contains(t, getItems, "#if( $expression == \"\" )\n    #return([])\n#end")
+
+	// Nested items are keyed by the parent field declared
+	nested := string(files["_keeper_animals.tf"])
+	assert.Contains(t, nested, `#set( $args = {"keeperId":"id"} )`)
+	assert.Contains(t, nested, `#set( $value = $ctx.source.get($args.get($key)) )`)
+
+	list := string(files["_query_listanimals.tf"])
+	assert.Contains(t, list, `"index" : "byKeeper",`)
+}
+
+var dynamoIndexManifest = []byte(`
+sources:
+  default:
+    name: animals
+    dynamo:
+      hash_key:
+        name: zooId
+      sort_key:
+        name: id
+      gsi:
+        - name: bySpecies
+          hash_key:
+            name: species
+          sort_key:
+            name: born
+            type: N
+        - name: byKeeper
+          hash_key:
+            name: keeperId
+          projection: INCLUDE
+          non_key_attributes: [name]
+      lsi:
+        - name: byName
+          sort_key:
+            name: name
+          projection: KEYS_ONLY
+objects:
+  - name: Animal
+    fields:
+      - name: zooId
+        type: ID!
+      - name: id
+        type: ID!
+      - name: species
+        type: String
+      - name: born
+        type: Int
+      - name: keeperId
+        type: ID
+      - name: name
+        type: String
+  - name: Keeper
+    fields:
+      - name: id
+        type: ID!
+      - name: animals
+        resolver:
+          action: get-items
+          type: Animal
+          index: byKeeper
+          keyFields:
+            - name: keeperId
+              parent: id
+queries:
+  - name: animalsBySpecies
+    resolver:
+      action: get-items
+      type: Animal
+      index: bySpecies
+      keyFields:
+        - name: species
+          type: String
+        - name: born
+          type: Int
+  - name: listAnimals
+    resolver:
+      action: list
+      type: [Animal]
+      index: byKeeper
+`)
+
+func TestGenerateDynamoTable(t *testing.T) {
+	files, err := mustCompileSchema(t, dynamoTableManifest).Generate()
+	if err != nil {
+		t.Fatalf("error generating schema '%v', expected nil", err)
+	}
+
+	source := string(files["_datasource_dynamo_animals.tf"])
+	assert.Contains(t, source, "billing_mode \t= \"PROVISIONED\"\n\tread_capacity\t= 5\n\twrite_capacity\t= 2\n")
+	assert.Contains(t, source, "projection_type\t= \"ALL\"\n\t\tread_capacity\t= 5\n\t\twrite_capacity\t= 2\n\t}")
+	assert.Contains(t, source, "table_class\t\t= \"STANDARD_INFREQUENT_ACCESS\"\n\tdeletion_protection_enabled = true\n")
+	assert.Contains(t, source, "stream_enabled\t\t= true\n\tstream_view_type\t= \"NEW_AND_OLD_IMAGES\"\n")
+	assert.Contains(t, source, "server_side_encryption {\n\t\tenabled     = true\n\t\tkms_key_arn = \"arn:aws:kms:eu-west-1:123456789012:key/zoo\"\n\t}")
+	assert.Contains(t, source, "lifecycle {\n\t\tignore_changes = [read_capacity, write_capacity]\n\t}")
+	assert.Contains(t, source, "Name        = \"animals\"\n\t\t\"cost-centre\" = \"42\"\n\t\t\"team\" = \"zoo\"\n\t}")
+	assert.Contains(t, source, "resource \"aws_appautoscaling_target\" \"animals_read\" {\n\tmin_capacity\t\t= 5\n\tmax_capacity\t\t= 50\n")
+	assert.Contains(t, source, "scalable_dimension\t= \"dynamodb:table:WriteCapacityUnits\"")
+	assert.Contains(t, source, "predefined_metric_type = \"DynamoDBWriteCapacityUtilization\"\n\t\t}\n\t\ttarget_value = 60\n")
+
+	// Tables without settings keep to the defaults
+	keepers := string(files["_datasource_dynamo_keepers.tf"])
+	assert.Contains(t, keepers, "billing_mode \t= \"PAY_PER_REQUEST\"")
+	for _, setting := range []string{"read_capacity", "table_class", "deletion_protection", "stream_enabled", "server_side_encryption", "lifecycle", "aws_appautoscaling"} {
+		assert.NotContains(t, keepers, setting)
+	}
+}
+
+func TestCloudFormationDynamoTable(t *testing.T) {
+	original := graphql.Target
+	graphql.Target = graphql.TargetCloudFormation
+	defer func() { graphql.Target = original }()
+
+	files, err := mustCompileSchema(t, dynamoTableManifest).Generate()
+	if err != nil {
+		t.Fatalf("error generating schema '%v', expected nil", err)
+	}
+
+	template := string(files["template.yaml"])
+	assert.Contains(t, template, "      BillingMode: PROVISIONED\n      ProvisionedThroughput:\n        ReadCapacityUnits: 5\n        WriteCapacityUnits: 2\n")
+	assert.Contains(t, template, "            ProjectionType: ALL\n          ProvisionedThroughput:\n")
+	assert.Contains(t, template, "      TableClass: STANDARD_INFREQUENT_ACCESS\n      DeletionProtectionEnabled: true\n")
+	assert.Contains(t, template, "      SSESpecification:\n        SSEEnabled: true\n        SSEType: KMS\n        KMSMasterKeyId: \"arn:aws:kms:eu-west-1:123456789012:key/zoo\"\n")
+	assert.Contains(t, template, "      StreamSpecification:\n        StreamViewType: NEW_AND_OLD_IMAGES\n")
+	assert.Contains(t, template, "        - Key: \"team\"\n          Value: \"zoo\"\n")
+	assert.Contains(t, template, "  AnimalsReadScalableTarget:\n    Type: AWS::ApplicationAutoScaling::ScalableTarget\n")
+	assert.Contains(t, template, "      ScalingTargetId: !Ref AnimalsWriteScalableTarget\n      TargetTrackingScalingPolicyConfiguration:\n        TargetValue: 60\n")
+	assert.NotContains(t, template, "KeepersReadScalableTarget")
+}
+
+var dynamoTableManifest = []byte(`
+sources:
+  default:
+    name: animals
+    dynamo:
+      hash_key:
+        name: id
+      gsi:
+        - name: bySpecies
+          hash_key:
+            name: species
+      billing_mode: PROVISIONED
+      capacity:
+        read: 5
+        write: 2
+        autoscaling:
+          max_read: 50
+          max_write: 20
+          target_utilization: 60
+      encryption:
+        kms_key_arn: arn:aws:kms:eu-west-1:123456789012:key/zoo
+      stream: NEW_AND_OLD_IMAGES
+      table_class: STANDARD_INFREQUENT_ACCESS
+      deletion_protection: true
+      tags:
+        team: zoo
+        cost-centre: "42"
+  keepers:
+    name: keepers
+    dynamo:
+      hash_key:
+        name: id
+objects:
+  - name: Animal
+    fields:
+      - name: id
+        type: ID!
+      - name: species
+        type: String
+  - name: Keeper
+    fields:
+      - name: id
+        type: ID!
+queries:
+  - name: getAnimal
+    resolver:
+      action: get
+      type: Animal
+      keyFields:
+        - name: id
+  - name: getKeeper
+    resolver:
+      action: get
+      type: Keeper
+      source: keepers
+      keyFields:
+        - name: id
+`)
+
+var eventBridgeManifest = []byte(`
+sources:
+  default:
+    name: zoo_events
+    eventbridge:
+      bus_arn: arn:aws:events:eu-west-2:123456789012:event-bus/zoo
+      source: com.example.zoo
+      detail_type: AnimalFed
+  named:
+    name: zoo_named_events
+    eventbridge:
+      bus_arn: arn:aws:events:eu-west-2:123456789012:event-bus/zoo
+      source: com.example.zoo
+  local:
+    name: local
+    none: {}
+objects:
+  - name: Animal
+    fields:
+      - name: id
+        type: ID!
+      - name: enclosure
+        type: String
+queries:
+  - name: getAnimal
+    resolver:
+      action: local
+      type: Animal
+      source: local
+      keyFields:
+        - name: id
+          type: ID!
+mutations:
+  - name: animalFed
+    resolver:
+      action: publish
+      type: Animal
+  - name: animalNamed
+    resolver:
+      action: publish
+      type: Animal
+      source: named
+  - name: animalMoved
+    resolver:
+      action: local
+      type: Animal
+      source: local
+      keyFields:
+        - name: id
+          type: ID!
+        - name: enclosure
+          type: String
+`)
+
+var ttlManifest = []byte(`
+sources:
+  default:
+    name: sessions
+    dynamo:
+      hash_key:
+        name: id
+      ttl:
+        attribute: ttl
+  tokens:
+    name: tokens
+    dynamo:
+      hash_key:
+        name: id
+      ttl:
+        attribute: expiresAt
+objects:
+  - name: Session
+    fields:
+      - name: id
+        type: ID!
+      - name: expiresAt
+        type: AWSDateTime
+        ttl:
+          defaultLifetime: 3600
+  - name: Token
+    fields:
+      - name: id
+        type: ID!
+      - name: expiresAt
+        type: AWSTimestamp
+        ttl: {}
+mutations:
+  - name: createSession
+    resolver:
+      action: insert
+      type: Session
+      keyFields:
+        - name: id
+  - name: updateSession
+    resolver:
+      action: update
+      type: Session
+      keyFields:
+        - name: id
+  - name: createToken
+    resolver:
+      action: insert
+      type: Token
+      source: tokens
+      keyFields:
+        - name: id
+`)
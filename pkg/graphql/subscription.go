@@ -0,0 +1,99 @@
+package graphql
+
+import (
+	"fmt"
+	"strings"
+)
+
+type (
+	// Subscription represents the definition of a graphql subscription
+	//
+	//   Subscription {
+	//   - name: <subscription_name>
+	//     mutations: [<mutation_name>, ...]
+	//     arguments: # optional
+	//       - name: <field_name>
+	//         type: <field_type>
+	//     enhancedFiltering: <true|false> # optional
+	//     auth: [<auth_type>, ...] # optional
+	//   ...
+	//   }
+	//
+	Subscription struct {
+		Name string `yaml:"name"`
+
+		// (Optional) The type returned by the subscription. Defaults to the
+		// type returned by the subscribed mutations
+		Type *FieldType `yaml:"type"`
+
+		// Names of the mutations that trigger the subscription
+		Mutations []string `yaml:"mutations"`
+
+		// (Optional) Arguments the subscriber may supply to filter the
+		// results. Each must be a field of the returned type.
+		Arguments []*Field `yaml:"arguments"`
+
+		// If true, arguments are applied as enhanced subscription filters
+		// by a resolver attached to a NONE data source
+		EnhancedFiltering bool `yaml:"enhancedFiltering"`
+
+		// (Optional) Authorization types allowed to subscribe
+		Auth AuthList `yaml:"auth"`
+
+		// Set automatically where enhanced filtering is requested
+		Resolver *Resolver `yaml:"-"`
+	}
+)
+
+// ArgsString returns the subscription arguments in a string format suitable
+// to be used as the arguments list in the schema
+func (s *Subscription) ArgsString() string {
+	if len(s.Arguments) == 0 {
+		return ""
+	}
+	al := make([]string, len(s.Arguments))
+	for i, a := range s.Arguments {
+		al[i] = fmt.Sprintf("%s: %s", a.Name, a.Type.Name)
+	}
+	return "(" + strings.Join(al, ", ") + ")"
+}
+
+// MutationsString returns the subscribed mutations in a string format
+// suitable for the `@aws_subscribe` directive
+func (s *Subscription) MutationsString() string {
+	ml := make([]string, len(s.Mutations))
+	for i, m := range s.Mutations {
+		ml[i] = fmt.Sprintf(`"%s"`, m)
+	}
+	return "[" + strings.Join(ml, ", ") + "]"
+}
+
+// mutation returns the named mutation, or nil if it doesn't exist
+func (s *Schema) mutation(name string) *Mutation {
+	for _, m := range s.Mutations {
+		if m.Name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// noneSource returns the NONE data source used by local resolvers, creating
+// it if one hasn't been declared in the manifest. Where several are declared
+// the first by source key is used, so the output is reproducible
+func (s *Schema) noneSource() (*Source, error) {
+	for _, ds := range s.sortedSources() {
+		if ds.Type == "none" {
+			return ds, nil
+		}
+	}
+	if _, ok := s.Sources["none"]; ok {
+		return nil, fmt.Errorf("source key 'none' is reserved for the NONE data source")
+	}
+	if s.Sources == nil {
+		s.Sources = map[string]*Source{}
+	}
+	ds := &Source{Name: "none", Type: "none"}
+	s.Sources["none"] = ds
+	return ds, nil
+}
@@ -0,0 +1,82 @@
+package graphql_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateSubscriptions(t *testing.T) {
+	files, err := mustCompileSchema(t, subscriptionManifest).Generate()
+	if err != nil {
+		t.Fatalf("error generating schema '%v', expected nil", err)
+	}
+
+	schema := string(files["schema.public.graphql"])
+	assert.Contains(t, schema, `onAnimalChanged(species: String): Animal @aws_subscribe(mutations: ["createAnimal", "updateAnimal"])`)
+	assert.Contains(t, schema, `onAnimalDeleted: Animal @aws_subscribe(mutations: ["deleteAnimal"])`)
+
+	// Only subscriptions with enhanced filtering have a resolver
+	assert.Contains(t, string(files["_subscription_onanimalchanged.tf"]), "$extensions.setSubscriptionFilter(")
+	assert.NotContains(t, files, "_subscription_onanimaldeleted.tf")
+	assert.Contains(t, string(files["_datasource_none_none.tf"]), `type				= "NONE"`)
+}
+
+func TestSubscriptionsUseFirstNoneSource(t *testing.T) {
+	manifest := bytes.Replace(subscriptionManifest, []byte("sources:\n"), []byte("sources:\n  local:\n    name: local\n    none: {}\n  echo:\n    name: echo\n    none: {}\n"), 1)
+
+	for i := 0; i < 10; i++ {
+		files, err := mustCompileSchema(t, manifest).Generate()
+		if err != nil {
+			t.Fatalf("error generating schema '%v', expected nil", err)
+		}
+		assert.Contains(t, string(files["_subscription_onanimalchanged.tf"]), "aws_appsync_datasource.echo.name")
+		assert.NotContains(t, files, "_datasource_none_none.tf")
+	}
+}
+
+var subscriptionManifest = []byte(`
+sources:
+  default:
+    name: animals
+    dynamo:
+      hash_key:
+        name: id
+
+objects:
+  - name: Animal
+    fields:
+      - name: id
+        type: ID!
+      - name: species
+
+mutations:
+  - name: createAnimal
+    resolver:
+      action: insert
+      type: Animal
+  - name: updateAnimal
+    resolver:
+      action: update
+      type: Animal
+      keyFields:
+        - name: id
+          type: ID
+  - name: deleteAnimal
+    resolver:
+      action: delete
+      type: Animal
+      keyFields:
+        - name: id
+          type: ID
+
+subscriptions:
+  - name: onAnimalChanged
+    mutations: [createAnimal, updateAnimal]
+    arguments:
+      - name: species
+    enhancedFiltering: true
+  - name: onAnimalDeleted
+    mutations: [deleteAnimal]
+`)
@@ -0,0 +1,38 @@
+package graphql
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/ONSdigital/aws-appsync-generator/templates"
+)
+
+// TemplatesPath is an optional directory of resolver templates. Any template
+// found here overrides the built-in template with the same
+// `<source>/<request|response>/<action>.tmpl` path, or `http/<request|response>.tmpl`
+// for the templates shared by every http action.
+var TemplatesPath = ""
+
+// loadResolverTemplate returns the content of the resolver template at the
+// given path, preferring any override in `TemplatesPath` over the built-in
+// template
+func loadResolverTemplate(name string) (string, error) {
+	if TemplatesPath != "" {
+		b, err := ioutil.ReadFile(filepath.Join(TemplatesPath, filepath.FromSlash(name)))
+		if err == nil {
+			return string(b), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+
+	b, err := templates.Resolvers.ReadFile(path.Join("resolvers", name))
+	if err != nil {
+		return "", fmt.Errorf("no resolver template found for '%s'", name)
+	}
+	return string(b), nil
+}
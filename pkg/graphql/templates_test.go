@@ -0,0 +1,202 @@
+package graphql_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ONSdigital/aws-appsync-generator/pkg/graphql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplatesPathOverridesBuiltIn(t *testing.T) {
+	dir, err := ioutil.TempDir("", "templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.MkdirAll(filepath.Join(dir, "dynamo", "response"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	override := `{{define "response" -}}overridden {{ .FieldName }}{{- end}}`
+	if err := ioutil.WriteFile(filepath.Join(dir, "dynamo", "response", "get.tmpl"), []byte(override), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	original := graphql.TemplatesPath
+	defer func() { graphql.TemplatesPath = original }()
+
+	for _, c := range []struct {
+		scenario      string
+		templatesPath string
+		expected      string
+		unexpected    string
+	}{
+		{"Built-in templates", "", "$util.toJson($ctx.result)", "overridden"},
+		{"Overridden response template", dir, "overridden getPerson", "$util.toJson($ctx.result)"},
+	} {
+		graphql.TemplatesPath = c.templatesPath
+		files, err := mustCompileSchema(t, overrideManifest).Generate()
+		if err != nil {
+			t.Fatalf("%s: error generating schema '%v', expected nil", c.scenario, err)
+		}
+		resolver := string(files["_query_getperson.tf"])
+
+		// The request template is never overridden
+		assert.Contains(t, resolver, `"operation": "GetItem"`, c.scenario)
+		assert.Contains(t, resolver, c.expected, c.scenario)
+		assert.NotContains(t, resolver, c.unexpected, c.scenario)
+	}
+}
+
+func TestTemplatesPathOverridesSharedHTTP(t *testing.T) {
+	dir, err := ioutil.TempDir("", "templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.MkdirAll(filepath.Join(dir, "http"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	override := `{{define "response" -}}overridden {{ .FieldName }}{{- end}}`
+	if err := ioutil.WriteFile(filepath.Join(dir, "http", "response.tmpl"), []byte(override), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	original := graphql.TemplatesPath
+	graphql.TemplatesPath = dir
+	defer func() { graphql.TemplatesPath = original }()
+
+	files, err := mustCompileSchema(t, httpManifest).Generate()
+	if err != nil {
+		t.Fatalf("error generating schema '%v', expected nil", err)
+	}
+
+	// Every http action shares the overridden template
+	for name, field := range map[string]string{
+		"_query_getanimal.tf":       "getAnimal",
+		"_mutation_createanimal.tf": "createAnimal",
+		"_mutation_updateanimal.tf": "updateAnimal",
+	} {
+		assert.Contains(t, string(files[name]), "overridden "+field, name)
+	}
+}
+
+func TestBuiltInTemplates(t *testing.T) {
+	files, err := mustCompileSchema(t, builtInTemplatesManifest).Generate()
+	if err != nil {
+		t.Fatalf("error generating schema '%v', expected nil", err)
+	}
+
+	for _, name := range []string{
+		"_query_getcorrespondence.tf",
+		"_query_listpeople.tf",
+		"_query_listcorrespondence.tf",
+		"_mutation_updateperson.tf",
+		"_correspondence_recipients.tf",
+	} {
+		// Every resolver renders both of its templates
+		resolver := string(files[name])
+		assert.Contains(t, resolver, "request_template  = <<EOF\n", name)
+		assert.Contains(t, resolver, "response_template = <<EOF\n", name)
+		assert.NotContains(t, resolver, "<<EOF\n\nEOF", name)
+	}
+}
+
+var overrideManifest = []byte(`
+sources:
+  default:
+    name: people
+    dynamo:
+      hash_key:
+        name: id
+objects:
+  - name: Person
+    fields:
+      - name: id
+        type: ID!
+queries:
+  - name: getPerson
+    resolver:
+      action: get
+      type: Person
+      keyFields:
+        - name: id
+`)
+
+var builtInTemplatesManifest = []byte(`
+sources:
+  people:
+    name: people
+    dynamo:
+      hash_key:
+        name: id
+  places:
+    name: places
+    dynamo:
+      hash_key:
+        name: id
+  things:
+    name: things
+    sql:
+      cluster_arn: cluster
+      secret_arn: secret
+      database: db
+
+enums:
+  - name: Channel
+    values: [EMAIL,LETTER,OTHER]
+
+objects:
+  - name: Correspondence
+    fields:
+      - name: reference
+        type: ID!
+      - name: channel
+        type: Channel
+      - name: recipients
+        resolver:
+          action: list
+          type: [Person]
+          source: people
+          keyFields:
+            - name: id
+              parent: reference
+
+  - name: Person
+    fields:
+      - name: id
+        type: ID!
+      - name: name
+
+queries:
+  - name: getCorrespondence
+    resolver:
+      action: get
+      type: Correspondence
+      source: things
+      keyFields:
+        - name: reference
+  - name: listPeople
+    resolver:
+      action: list
+      type: [Person]
+      source: people
+  - name: listCorrespondence
+    resolver:
+      action: list
+      type: [Correspondence]
+      source: things
+
+mutations:
+  - name: updatePerson
+    resolver:
+      action: update
+      type: Person
+      source: people
+      keyFields:
+        - name: id
+`)
@@ -0,0 +1,232 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Constants for the operations of transact steps
+const (
+	TransactPut            = "put"
+	TransactUpdate         = "update"
+	TransactDelete         = "delete"
+	TransactConditionCheck = "condition-check"
+)
+
+var (
+	reTransactOperations = regexp.MustCompile(`^(put|update|delete|condition-check)$`)
+	reTransactItem       = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+	// transactOperations gives the TransactWriteItems operation of each step
+	transactOperations = map[string]string{
+		TransactPut:            "PutItem",
+		TransactUpdate:         "UpdateItem",
+		TransactDelete:         "DeleteItem",
+		TransactConditionCheck: "ConditionCheck",
+	}
+)
+
+type (
+	// TransactStep describes one of the writes made atomically by a
+	// transact resolver
+	//
+	//   transact:
+	//     - operation: <put|update|delete|condition-check>
+	//       source: <dynamo_source> # optional
+	//       item: input.animals # optional
+	//       exclude: [<attribute>] # optional
+	//       condition: "#status = :open" # optional
+	//       names: {"#status": status} # optional
+	//       values: {":open": OPEN} # optional
+	//
+	TransactStep struct {
+		// One of put, update, delete or condition-check
+		Operation string `yaml:"operation"`
+
+		// Name of the dynamo source written to. Defaults to the source of
+		// the resolver
+		SourceKey string `yaml:"source"`
+
+		// Path of the argument holding the item, or list of items, written
+		// by the step, e.g. input.animals. Defaults to `input`. A step is
+		// made for every item of a list.
+		Item string `yaml:"item"`
+
+		// Attributes of the item that aren't written, e.g. a list of
+		// children put by a later step
+		Exclude []string `yaml:"exclude"`
+
+		// Condition expression which must hold for the transaction to go
+		// ahead. Required for condition-check steps
+		Condition string `yaml:"condition"`
+
+		// Attribute names and values substituted for the placeholders of
+		// the condition, e.g. {"#status": "status"} and {":open": "OPEN"}
+		Names  map[string]string      `yaml:"names"`
+		Values map[string]interface{} `yaml:"values"`
+
+		// Set automatically as the schema is parsed
+		DataSource *Source
+		Object     *Object // Object of the item, where the schema declares it
+	}
+
+	unmarshalTransactStep TransactStep
+)
+
+// UnmarshalYAML satisfies the custom unmarshaler interface for go-yaml. It is
+// called automatically by the YAML unmarshal to set and check defaults.
+func (t *TransactStep) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var u unmarshalTransactStep
+	if err := unmarshal(&u); err != nil {
+		return err
+	}
+
+	*t = TransactStep(u)
+
+	if !reTransactOperations.MatchString(t.Operation) {
+		return fmt.Errorf("transact operation '%s' must be one of put, update, delete or condition-check", t.Operation)
+	}
+	if t.Item == "" {
+		t.Item = "input"
+	}
+	if !reTransactItem.MatchString(t.Item) {
+		return fmt.Errorf("transact item '%s' must be the path of an argument, e.g. input.animals", t.Item)
+	}
+	if t.Operation == TransactConditionCheck && t.Condition == "" {
+		return fmt.Errorf("transact condition-check steps must declare a condition")
+	}
+	if t.Condition == "" && (len(t.Names) > 0 || len(t.Values) > 0) {
+		return fmt.Errorf("transact steps must declare a condition to use names or values")
+	}
+
+	// Placeholders are checked in order so errors are reported consistently
+	names := make([]string, 0, len(t.Names))
+	for n := range t.Names {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		if !strings.HasPrefix(n, "#") {
+			return fmt.Errorf("transact condition name '%s' must start with #", n)
+		}
+	}
+	values := make([]string, 0, len(t.Values))
+	for v := range t.Values {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	for _, v := range values {
+		if !strings.HasPrefix(v, ":") {
+			return fmt.Errorf("transact condition value '%s' must start with :", v)
+		}
+		switch t.Values[v].(type) {
+		case string, int, float64, bool:
+		default:
+			return fmt.Errorf("transact condition value '%s' must be a string, number or boolean", v)
+		}
+	}
+	return nil
+}
+
+// Argument returns the name of the field argument holding the item
+func (t *TransactStep) Argument() string {
+	return strings.SplitN(t.Item, ".", 2)[0]
+}
+
+// ItemPath returns the VTL reference to the item, or list of items, of the
+// step
+func (t *TransactStep) ItemPath() string {
+	return "$ctx.args." + t.Item
+}
+
+// DynamoOperation returns the TransactWriteItems operation made by the step
+func (t *TransactStep) DynamoOperation() string {
+	return transactOperations[t.Operation]
+}
+
+// ExcludeJSONList converts the excluded attributes into a JSON formatted
+// list of names
+func (t *TransactStep) ExcludeJSONList() string {
+	fl := make([]string, len(t.Exclude))
+	for i, e := range t.Exclude {
+		fl[i] = fmt.Sprintf(`"%s"`, e)
+	}
+	return "[" + strings.Join(fl, ",") + "]"
+}
+
+// NamesJSONMap converts the names of the condition into a JSON formatted map
+func (t *TransactStep) NamesJSONMap() string {
+	b, _ := json.Marshal(t.Names)
+	return string(b)
+}
+
+// ValuesJSONMap converts the values of the condition into a JSON formatted
+// map
+func (t *TransactStep) ValuesJSONMap() string {
+	// Values are checked to be scalars as they're unmarshalled
+	b, _ := json.Marshal(t.Values)
+	return string(b)
+}
+
+// IsVersioned reports whether the step writes a versioned object
+func (t *TransactStep) IsVersioned() bool {
+	return t.Object != nil && t.Object.Versioned
+}
+
+// Generated returns the fields of the step's object whose values the step
+// generates: all of them for puts, and only the update time for updates
+func (t *TransactStep) Generated() []*Field {
+	if t.Object == nil {
+		return nil
+	}
+	fl := []*Field{}
+	for _, f := range t.Object.Fields {
+		if f.Generated == "" {
+			continue
+		}
+		if t.Operation == TransactPut || (t.Operation == TransactUpdate && f.Generated == GeneratedUpdatedAt) {
+			fl = append(fl, f)
+		}
+	}
+	return fl
+}
+
+// transactObject returns the object of the item written by the step, found
+// by following its path through the fields of the object the resolver
+// returns, or nil where it isn't an object of the schema
+func (s *Schema) transactObject(r *Resolver, t *TransactStep) *Object {
+	o := s.objectLookup[r.Type.Name]
+	for _, name := range strings.Split(t.Item, ".")[1:] {
+		if o == nil {
+			return nil
+		}
+		f := o.field(name)
+		if f == nil || f.Type == nil {
+			return nil
+		}
+		o = s.objectLookup[f.Type.Name]
+	}
+	return o
+}
+
+// setTransactSources links each step of a transact resolver to the dynamo
+// source it writes to, and the object of its item
+func setTransactSources(r *Resolver, s *Schema) error {
+	for i, t := range r.Transact {
+		t.Object = s.transactObject(r, t)
+		key := t.SourceKey
+		if key == "" {
+			t.DataSource = r.DataSource
+			continue
+		}
+		ds, ok := s.Sources[key]
+		if !ok {
+			return fmt.Errorf("resolver '%s_%s' transact step %d has unknown data source '%s'", r.Parent, r.FieldName, i, key)
+		}
+		t.DataSource = ds
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package graphql_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ONSdigital/aws-appsync-generator/pkg/graphql"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestUnmarshalTransactStep(t *testing.T) {
+	for _, c := range []struct {
+		scenario string
+		yaml     []byte
+		err      error
+	}{
+		{
+			"Operation only",
+			[]byte("operation: put"),
+			nil,
+		},
+		{
+			"Bad operation",
+			[]byte("operation: insert"),
+			errors.New("transact operation 'insert' must be one of put, update, delete or condition-check"),
+		},
+		{
+			"Bad item",
+			[]byte("operation: delete\nitem: input.animals[0]"),
+			errors.New("transact item 'input.animals[0]' must be the path of an argument, e.g. input.animals"),
+		},
+		{
+			"Condition check without a condition",
+			[]byte("operation: condition-check\nitem: input.keeper"),
+			errors.New("transact condition-check steps must declare a condition"),
+		},
+		{
+			"Condition with names and values",
+			[]byte("operation: condition-check\ncondition: '#status = :open'\nnames: {'#status': status}\nvalues: {':open': OPEN}"),
+			nil,
+		},
+		{
+			"Names without a condition",
+			[]byte("operation: put\nnames: {'#status': status}"),
+			errors.New("transact steps must declare a condition to use names or values"),
+		},
+		{
+			"Bad name",
+			[]byte("operation: put\ncondition: 'status = :open'\nnames: {status: status}"),
+			errors.New("transact condition name 'status' must start with #"),
+		},
+		{
+			"Bad value",
+			[]byte("operation: put\ncondition: '#status = open'\nvalues: {open: OPEN}"),
+			errors.New("transact condition value 'open' must start with :"),
+		},
+		{
+			"Value that isn't a scalar",
+			[]byte("operation: put\ncondition: '#status IN :open'\nvalues: {':open': [OPEN]}"),
+			errors.New("transact condition value ':open' must be a string, number or boolean"),
+		},
+	} {
+		var s graphql.TransactStep
+		err := yaml.Unmarshal(c.yaml, &s)
+		switch c.err {
+		case nil:
+			assert.NoError(t, err, c.scenario)
+			assert.Equal(t, "input", s.Item, c.scenario)
+		default:
+			assert.EqualError(t, err, c.err.Error(), c.scenario)
+		}
+	}
+}
+
+func TestGenerateTransact(t *testing.T) {
+	files, err := mustCompileSchema(t, transactManifest).Generate()
+	if err != nil {
+		t.Fatalf("error generating schema '%v', expected nil", err)
+	}
+
+	schema := string(files["schema.public.graphql"])
+	assert.Contains(t, schema, "createZoo(input: CreateZooInput): Zoo")
+	assert.Contains(t, schema, "input CreateZooInput {\n\tzooId: ID\n\tname: String\n\tanimals: [CreateAnimalInput]\n\tkeeperId: ID\n\t}")
+
+	transact := string(files["_mutation_createzoo.tf"])
+	assert.Contains(t, transact, `data_source       = aws_appsync_datasource.zoos.name`)
+	assert.Contains(t, transact, `"operation": "TransactWriteItems",`)
+
+	// The zoo itself, without its animals
+	assert.Contains(t, transact, `#set( $items = $util.defaultIfNull($ctx.args.input, []) )`)
+	assert.Contains(t, transact, `#set( $exclude = ["animals","keeperId"] )`)
+	assert.Contains(t, transact, `#set( $transactItem = {"table": "${terraform.workspace}-zoos", "operation": "PutItem", "key": $key} )`)
+	assert.Contains(t, transact, `#set( $condition = {"expression": "attribute_not_exists(zooId)"} )`)
+
+	// Every animal
+	assert.Contains(t, transact, `#set( $items = $util.defaultIfNull($ctx.args.input.animals, []) )`)
+	assert.Contains(t, transact, `#set( $keyFields = ["zooId", "animalId"] )`)
+	assert.Contains(t, transact, `#set( $transactItem = {"table": "${terraform.workspace}-animals", "operation": "PutItem", "key": $key} )`)
+
+	// The keeper must exist
+	assert.Contains(t, transact, `#set( $transactItem = {"table": "${terraform.workspace}-keepers", "operation": "ConditionCheck", "key": $key} )`)
+	assert.Contains(t, transact, `#set( $condition = {"expression": "attribute_exists(keeperId) AND #status = :open"} )`)
+	assert.Contains(t, transact, `$util.qr($condition.put("expressionNames", {"#status":"status"}))`)
+	assert.Contains(t, transact, `$util.qr($condition.put("expressionValues", $util.dynamodb.toMapValues({":open":"OPEN"})))`)
+
+	assert.Contains(t, transact, `$util.error("Mutation.createZoo failed: transaction cancelled", "TransactionCanceled", null, {"reasons": $reasons})`)
+	assert.Contains(t, transact, `$util.toJson($ctx.args.input)`)
+
+	update := string(files["_mutation_renamezoo.tf"])
+	assert.Contains(t, update, `"operation": "UpdateItem", "key": $key} )`)
+	assert.Contains(t, update, `$util.qr($transactItem.put("update", $update))`)
+	assert.Contains(t, update, `{"expression": "attribute_exists(#keyExists)", "expressionNames": {"#keyExists": "zooId"}}`)
+	assert.Contains(t, update, `"operation": "DeleteItem", "key": $key} )`)
+}
+
+func TestCloudFormationTransactTableNames(t *testing.T) {
+	original := graphql.Target
+	graphql.Target = graphql.TargetCloudFormation
+	defer func() { graphql.Target = original }()
+
+	files, err := mustCompileSchema(t, transactManifest).Generate()
+	if err != nil {
+		t.Fatalf("error generating schema '%v', expected nil", err)
+	}
+
+	template := string(files["template.yaml"])
+	assert.Contains(t, template, "RequestMappingTemplate: !Sub |\n        #set( $transactItems = [] )")
+	assert.Contains(t, template, `"table": "${Environment}-animals"`)
+}
+
+var transactManifest = []byte(`
+sources:
+  default:
+    name: zoos
+    dynamo:
+      hash_key:
+        name: zooId
+  animals:
+    name: animals
+    dynamo:
+      hash_key:
+        name: zooId
+      sort_key:
+        name: animalId
+  keepers:
+    name: keepers
+    dynamo:
+      hash_key:
+        name: keeperId
+objects:
+  - name: Animal
+    fields:
+      - name: zooId
+        type: ID!
+      - name: animalId
+        type: ID!
+  - name: Zoo
+    fields:
+      - name: zooId
+        type: ID!
+      - name: name
+        type: String
+      - name: animals
+        type: [Animal]
+        inputType: CreateAnimalInput
+      - name: keeperId
+        type: ID
+mutations:
+  - name: createAnimal
+    resolver:
+      action: insert
+      type: Animal
+      source: animals
+  - name: createZoo
+    resolver:
+      action: transact
+      type: Zoo
+      transact:
+        - operation: put
+          exclude: [animals, keeperId]
+          condition: attribute_not_exists(zooId)
+        - operation: put
+          source: animals
+          item: input.animals
+        - operation: condition-check
+          source: keepers
+          condition: "attribute_exists(keeperId) AND #status = :open"
+          names:
+            "#status": status
+          values:
+            ":open": OPEN
+  - name: renameZoo
+    resolver:
+      action: transact
+      type: Zoo
+      transact:
+        - operation: update
+          exclude: [animals, keeperId]
+        - operation: delete
+          source: animals
+          item: input.animals
+`)
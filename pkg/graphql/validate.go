@@ -0,0 +1,642 @@
+package graphql
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+var reScalarTypes = regexp.MustCompile(`^(ID|String|Int|Float|Boolean|AWS(Date(Time)?|Time(stamp)?|Email|URL|Phone|IPAddress|JSON))$`)
+
+// supportedActions lists the resolver actions available for each data source type
+var supportedActions = map[string][]string{
+	"dynamo": {ActionGet, ActionGetItems, ActionList, ActionQuery, ActionInsert, ActionUpdate, ActionDelete, ActionBatchGet, ActionBatchInsert, ActionBatchDelete, ActionTransact},
+	"sql":    {ActionGet, ActionList, ActionInsert, ActionUpdate, ActionDelete},
+	"lambda": {ActionInvoke, ActionBatchInvoke},
+	"http":   {ActionGet, ActionInsert, ActionUpdate, ActionDelete},
+
+	"opensearch":  {ActionSearch},
+	"eventbridge": {ActionPublish},
+	"none":        {ActionLocal},
+}
+
+// Kinds of field a resolver may be attached to, which decide the arguments
+// available to its action
+const (
+	parentQuery    = "Query"
+	parentMutation = "Mutation"
+	parentObject   = "object"
+	parentFunction = "function"
+)
+
+type (
+	// ValidationError describes a semantic problem with a manifest. Path
+	// locates the offending element in the manifest (e.g.
+	// `queries[0].resolver.type`) and Line and Column give its position in
+	// the YAML source, where it could be determined.
+	ValidationError struct {
+		Path    string
+		Line    int
+		Column  int
+		Message string
+	}
+
+	validator struct {
+		s      *Schema
+		errors []*ValidationError
+	}
+)
+
+func (e *ValidationError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%d:%d: %s: %s", e.Line, e.Column, e.Path, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// Validate runs a semantic validation pass over the schema, returning every
+// problem found. It does not modify the schema or touch the filesystem so is
+// safe to run before any output is generated.
+func (s *Schema) Validate() []*ValidationError {
+	v := &validator{s: s}
+
+	v.validateNames()
+	v.validateObjects()
+	v.validateFunctions()
+
+	for i, q := range s.Queries {
+		v.validateResolver(fmt.Sprintf("queries[%d]", i), q.Resolver, parentQuery)
+	}
+	for i, m := range s.Mutations {
+		v.validateResolver(fmt.Sprintf("mutations[%d]", i), m.Resolver, parentMutation)
+	}
+	for i, sub := range s.Subscriptions {
+		v.validateSubscription(fmt.Sprintf("subscriptions[%d]", i), sub)
+	}
+
+	v.validateAuth()
+
+	v.locate(s.manifest)
+	return v.errors
+}
+
+func (v *validator) addError(path, format string, args ...interface{}) {
+	v.errors = append(v.errors, &ValidationError{
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// validateNames checks there are no clashes between the names of types,
+// queries and mutations
+func (v *validator) validateNames() {
+	types := map[string]string{}
+	for i, e := range v.s.Enums {
+		path := fmt.Sprintf("enums[%d].name", i)
+		if other, ok := types[e.Name]; ok {
+			v.addError(path, "duplicate type name '%s' (also declared at %s)", e.Name, other)
+			continue
+		}
+		types[e.Name] = path
+	}
+	for i, o := range v.s.Objects {
+		path := fmt.Sprintf("objects[%d].name", i)
+		if other, ok := types[o.Name]; ok {
+			v.addError(path, "duplicate type name '%s' (also declared at %s)", o.Name, other)
+			continue
+		}
+		types[o.Name] = path
+	}
+
+	queries := map[string]string{}
+	for i, q := range v.s.Queries {
+		path := fmt.Sprintf("queries[%d].name", i)
+		if other, ok := queries[q.Name]; ok {
+			v.addError(path, "duplicate query name '%s' (also declared at %s)", q.Name, other)
+			continue
+		}
+		queries[q.Name] = path
+	}
+
+	mutations := map[string]string{}
+	for i, m := range v.s.Mutations {
+		path := fmt.Sprintf("mutations[%d].name", i)
+		if other, ok := mutations[m.Name]; ok {
+			v.addError(path, "duplicate mutation name '%s' (also declared at %s)", m.Name, other)
+			continue
+		}
+		mutations[m.Name] = path
+	}
+
+	subscriptions := map[string]string{}
+	for i, sub := range v.s.Subscriptions {
+		path := fmt.Sprintf("subscriptions[%d].name", i)
+		if other, ok := subscriptions[sub.Name]; ok {
+			v.addError(path, "duplicate subscription name '%s' (also declared at %s)", sub.Name, other)
+			continue
+		}
+		subscriptions[sub.Name] = path
+	}
+}
+
+func (v *validator) validateObjects() {
+	for i, o := range v.s.Objects {
+		ttl := ""
+		for j, f := range o.Fields {
+			path := fmt.Sprintf("objects[%d].fields[%d]", i, j)
+			if f.TTL != nil {
+				if ttl != "" {
+					v.addError(path+".ttl", "object '%s' already declares field '%s' as its ttl", o.Name, ttl)
+				}
+				ttl = f.Name
+			}
+			if f.Resolver != nil {
+				v.validateResolver(path, f.Resolver, parentObject)
+				continue
+			}
+			if f.Type != nil && !v.isKnownType(f.Type.Name) {
+				v.addError(path+".type", "unknown type '%s'", f.Type.Name)
+			}
+			if f.InputType != nil && !v.isKnownType(f.InputType.Name) && !v.isGeneratedInput(f.InputType.Name) {
+				v.addError(path+".inputType", "unknown type '%s'", f.InputType.Name)
+			}
+		}
+	}
+}
+
+func (v *validator) validateResolver(path string, r *Resolver, parent string) {
+	if r == nil {
+		v.addError(path, "does not declare a resolver")
+		return
+	}
+	path += ".resolver"
+
+	if r.Type == nil {
+		v.addError(path, "resolver does not declare a type")
+		return
+	}
+
+	if r.IsPipeline() {
+		v.validatePipeline(path, r, parent)
+		return
+	}
+	v.validateAction(path, r, parent)
+}
+
+// validatePipeline checks a pipeline resolver only refers to functions that
+// exist and can be used by the kind of field it is attached to, and that it
+// doesn't also declare a data source of its own
+func (v *validator) validatePipeline(path string, r *Resolver, parent string) {
+	if r.SourceKey != "" {
+		v.addError(path+".source", "pipeline resolvers cannot declare a source, each function has its own")
+	}
+	if r.Action == ActionList && !r.Type.IsList {
+		v.addError(path+".type", "list actions must declare a list type, e.g. [%s]", r.Type.Name)
+	}
+	if !v.isKnownType(r.Type.Name) {
+		v.addError(path+".type", "unknown type '%s'", r.Type.Name)
+	}
+	for i, name := range r.Pipeline {
+		f := v.s.function(name)
+		if f == nil {
+			v.addError(fmt.Sprintf("%s.pipeline[%d]", path, i), "unknown function '%s'", name)
+			continue
+		}
+		if msg := parentError(f.Action, parent); msg != "" {
+			v.addError(fmt.Sprintf("%s.pipeline[%d]", path, i), "function '%s': %s", name, msg)
+		}
+	}
+}
+
+// validateFunctions checks function names are unique and that any built in
+// action is valid for the function's data source
+func (v *validator) validateFunctions() {
+	names := map[string]string{}
+	for i, f := range v.s.Functions {
+		path := fmt.Sprintf("functions[%d]", i)
+		if other, ok := names[f.Name]; ok {
+			v.addError(path+".name", "duplicate function name '%s' (also declared at %s)", f.Name, other)
+		} else {
+			names[f.Name] = path + ".name"
+		}
+
+		r := &Resolver{
+			Action:    f.Action,
+			Type:      f.Type,
+			KeyFields: f.KeyFields,
+			SourceKey: f.SourceKey,
+		}
+		if f.Action == "" {
+			v.source(path, r)
+			continue
+		}
+		v.validateAction(path, r, parentFunction)
+	}
+}
+
+// validateAction checks the resolver's action is valid for its type, keys
+// and data source, and for the kind of field it is attached to
+func (v *validator) validateAction(path string, r *Resolver, parent string) {
+	o, isObject := v.s.objectLookup[r.Type.Name]
+
+	// Functions take the arguments of the top level fields using them
+	topLevel := parent != parentObject
+	if !v.isKnownType(r.Type.Name) {
+		v.addError(path+".type", "unknown type '%s'", r.Type.Name)
+	}
+
+	if (r.Action == ActionList || r.Action == ActionQuery || r.Action == ActionSearch || r.IsBatch()) && !r.Type.IsList {
+		v.addError(path+".type", "%s actions must declare a list type, e.g. [%s]", r.Action, r.Type.Name)
+	}
+
+	if msg := parentError(r.Action, parent); msg != "" {
+		v.addError(path+".action", msg)
+	}
+
+	if r.inputAction() != "" && topLevel && !isObject {
+		v.addError(path+".type", "%s actions must return an object type to take its input, not '%s'", r.Action, r.Type.Name)
+	}
+
+	if isObject {
+		for i, k := range r.KeyFields {
+			if !o.hasField(k.Name) {
+				v.addError(fmt.Sprintf("%s.keyFields[%d]", path, i), "key field '%s' is not a field of '%s'", k.Name, o.Name)
+			}
+		}
+	}
+
+	if (r.Action == ActionUpdate || r.Action == ActionDelete || r.Action == ActionQuery || r.Action == ActionBatchGet || r.Action == ActionBatchDelete) && len(r.KeyFields) == 0 {
+		v.addError(path, "%s actions must declare keyFields", r.Action)
+	}
+
+	// Nested batch gets read the items keyed by a list field of the parent
+	if r.Action == ActionBatchGet && parent == parentObject && len(r.KeyFields) > 0 && (len(r.KeyFields) > 1 || r.KeyFields[0].Parent == "") {
+		v.addError(path+".keyFields", "nested batch-get actions must declare a single key field with the parent field holding its values")
+	}
+
+	switch {
+	case r.Action == ActionTransact && len(r.Transact) == 0:
+		v.addError(path, "transact actions must declare the steps of the transaction")
+	case r.Action != ActionTransact && len(r.Transact) > 0:
+		v.addError(path+".transact", "transact steps can only be declared by transact actions")
+	}
+	for i, t := range r.Transact {
+		v.validateTransactStep(fmt.Sprintf("%s.transact[%d]", path, i), r, t)
+	}
+
+	ds := v.source(path, r)
+	if ds == nil {
+		return
+	}
+
+	if !isSupportedAction(ds.Type, r.Action) {
+		v.addError(path+".action", "action '%s' is not supported by %s source '%s'", r.Action, ds.Type, ds.Name)
+	}
+
+	if ds.Type == "sql" && isObject && o.Versioned {
+		v.addError(path+".type", "versioned object '%s' can only be stored by dynamo sources, not sql source '%s'", o.Name, ds.Name)
+	}
+
+	// Inserted rows are read back by the key given in the input
+	if ds.Type == "sql" && r.Action == ActionInsert && len(r.KeyFields) == 0 {
+		v.addError(path, "insert actions using sql source '%s' must declare the keyFields given in the input to read back the inserted row", ds.Name)
+	}
+
+	switch {
+	case ds.Type == "http" && r.HTTP == nil:
+		v.addError(path, "resolvers using http source '%s' must declare an http request", ds.Name)
+	case ds.Type != "http" && r.HTTP != nil:
+		v.addError(path+".http", "http requests can only be made to http sources, not %s source '%s'", ds.Type, ds.Name)
+	case r.HTTP != nil && topLevel:
+		v.validateHTTPArguments(path+".http", r)
+	}
+
+	if r.Index != "" {
+		switch {
+		case ds.Type != "dynamo":
+			v.addError(path+".index", "indexes can only be queried on dynamo sources, not %s source '%s'", ds.Type, ds.Name)
+			return
+		case r.Action != ActionGetItems && r.Action != ActionList && r.Action != ActionQuery:
+			v.addError(path+".index", "%s actions cannot use an index", r.Action)
+		case ds.Dynamo.index(r.Index) == nil:
+			v.addError(path+".index", "unknown index '%s' of dynamo source '%s'", r.Index, ds.Name)
+			return
+		}
+	}
+
+	// Top level lists scan the whole table so aren't keyed
+	if ds.Type == "dynamo" && !(topLevel && r.Action == ActionList) {
+		v.validateDynamoKeys(path, r, ds)
+	}
+
+	// The expiry time of the object is written to the ttl of the table
+	if ds.Type == "dynamo" && isObject && (r.Action == ActionInsert || r.Action == ActionUpdate) {
+		if f := o.ttlField(); f != nil {
+			switch {
+			case ds.Dynamo.TTL == nil:
+				v.addError(path, "dynamo source '%s' must declare a ttl to store the expiry of '%s'", ds.Name, o.Name)
+			case f.Name == ds.Dynamo.TTL.Attribute && f.Type.Name == "AWSDateTime":
+				v.addError(path, "ttl field '%s' of type AWSDateTime cannot be stored in ttl attribute '%s' of dynamo source '%s', which holds epoch seconds", f.Name, f.Name, ds.Name)
+			}
+		}
+	}
+
+	// Batches read and delete whole items, so need their full key
+	if ds.Type == "dynamo" && (r.Action == ActionBatchGet || r.Action == ActionBatchDelete) && len(r.KeyFields) == 1 && ds.Dynamo.SortKey != nil {
+		v.addError(path+".keyFields", "%s actions must declare both the hash and sort keys of dynamo source '%s'", r.Action, ds.Name)
+	}
+}
+
+func (v *validator) validateSubscription(path string, sub *Subscription) {
+	if len(sub.Mutations) == 0 {
+		v.addError(path+".mutations", "subscriptions must declare at least one mutation")
+	}
+
+	// Every subscribed mutation must return the same type
+	returns := ""
+	if sub.Type != nil {
+		returns = sub.Type.Name
+		if !v.isKnownType(returns) {
+			v.addError(path+".type", "unknown type '%s'", returns)
+		}
+	}
+	for i, name := range sub.Mutations {
+		mPath := fmt.Sprintf("%s.mutations[%d]", path, i)
+		m := v.s.mutation(name)
+		if m == nil {
+			v.addError(mPath, "unknown mutation '%s'", name)
+			continue
+		}
+		if m.Resolver == nil || m.Resolver.Type == nil {
+			continue
+		}
+		if returns == "" {
+			returns = m.Resolver.Type.Name
+			continue
+		}
+		if m.Resolver.Type.Name != returns {
+			v.addError(mPath, "mutation '%s' returns '%s' but the subscription returns '%s'", name, m.Resolver.Type.Name, returns)
+		}
+	}
+
+	o, isObject := v.s.objectLookup[returns]
+	for i, a := range sub.Arguments {
+		aPath := fmt.Sprintf("%s.arguments[%d]", path, i)
+		if isObject && !o.hasField(a.Name) {
+			v.addError(aPath, "argument '%s' is not a field of '%s'", a.Name, o.Name)
+		}
+		if a.Type == nil {
+			continue
+		}
+		if _, ok := v.s.objectLookup[a.Type.Name]; ok {
+			v.addError(aPath+".type", "subscription arguments must be scalar or enum types, not '%s'", a.Type.Name)
+		} else if !v.isKnownType(a.Type.Name) {
+			v.addError(aPath+".type", "unknown type '%s'", a.Type.Name)
+		}
+	}
+}
+
+// validateAuth checks that every auth directive uses an authorization type
+// the api is configured with
+func (v *validator) validateAuth() {
+	check := func(path string, l AuthList) {
+		for i, t := range l {
+			if !v.s.API.hasAuth(t) {
+				v.addError(fmt.Sprintf("%s.auth[%d]", path, i), "auth type '%s' is not configured for the api", t)
+			}
+		}
+	}
+	for i, o := range v.s.Objects {
+		path := fmt.Sprintf("objects[%d]", i)
+		check(path, o.Auth)
+		for j, f := range o.Fields {
+			check(fmt.Sprintf("%s.fields[%d]", path, j), f.Auth)
+		}
+	}
+	for i, q := range v.s.Queries {
+		check(fmt.Sprintf("queries[%d]", i), q.Auth)
+	}
+	for i, m := range v.s.Mutations {
+		check(fmt.Sprintf("mutations[%d]", i), m.Auth)
+	}
+	for i, sub := range v.s.Subscriptions {
+		check(fmt.Sprintf("subscriptions[%d]", i), sub.Auth)
+	}
+}
+
+// validateHTTPArguments checks the query parameters and body of an http
+// request refer to arguments of the field
+func (v *validator) validateHTTPArguments(path string, r *Resolver) {
+	args := map[string]bool{}
+	for _, k := range r.KeyFields {
+		args[k.Name] = true
+	}
+	h := r.HTTP.forAction(r.Action)
+	if r.Action == ActionInsert || r.Action == ActionUpdate {
+		args["input"] = true
+	}
+
+	params := make([]string, 0, len(h.Query))
+	for p := range h.Query {
+		params = append(params, p)
+	}
+	sort.Strings(params)
+	for _, p := range params {
+		if !args[h.Query[p]] {
+			v.addError(path+".query."+p, "query parameter '%s' refers to unknown argument '%s'", p, h.Query[p])
+		}
+	}
+	if h.Body != "" && !args[h.Body] {
+		v.addError(path+".body", "body refers to unknown argument '%s'", h.Body)
+	}
+}
+
+// validateTransactStep checks the step writes to a dynamo source from the
+// input of the mutation
+func (v *validator) validateTransactStep(path string, r *Resolver, t *TransactStep) {
+	if t.Argument() != "input" {
+		v.addError(path+".item", "item refers to unknown argument '%s'", t.Argument())
+	}
+
+	// Transactions take the create input, which has no version to check, and
+	// no generated ids to find an existing object by
+	if o := v.s.transactObject(r, t); o != nil && t.Operation != TransactPut {
+		if o.Versioned && (t.Operation == TransactUpdate || t.Operation == TransactDelete) {
+			v.addError(path+".operation", "transact %s steps cannot write versioned object '%s'", t.Operation, o.Name)
+		}
+		for _, f := range o.Fields {
+			if f.Generated != "" && f.generatesID() {
+				v.addError(path+".operation", "transact %s steps cannot find object '%s' by its generated field '%s'", t.Operation, o.Name, f.Name)
+			}
+		}
+	}
+	if t.SourceKey == "" {
+		return
+	}
+	ds, ok := v.s.Sources[t.SourceKey]
+	switch {
+	case !ok:
+		v.addError(path+".source", "unknown source '%s'", t.SourceKey)
+	case ds.Type != "dynamo":
+		v.addError(path+".source", "transact steps can only write to dynamo sources, not %s source '%s'", ds.Type, ds.Name)
+	}
+}
+
+// source returns the data source the resolver will use, raising an error if
+// there isn't one
+func (v *validator) source(path string, r *Resolver) *Source {
+	if r.SourceKey != "" {
+		if ds, ok := v.s.Sources[r.SourceKey]; ok {
+			return ds
+		}
+		v.addError(path+".source", "unknown source '%s'", r.SourceKey)
+		return nil
+	}
+	if ds, ok := v.s.Sources["default"]; ok {
+		return ds
+	}
+	v.addError(path, "resolver does not declare a source and there is no default source")
+	return nil
+}
+
+func (v *validator) validateDynamoKeys(path string, r *Resolver, ds *Source) {
+	keys := []*DynamoKeyType{ds.Dynamo.HashKey, ds.Dynamo.SortKey}
+	of := fmt.Sprintf("dynamo source '%s'", ds.Name)
+	if idx := ds.Dynamo.index(r.Index); idx != nil {
+		keys = []*DynamoKeyType{idx.HashKey, idx.SortKey}
+		of = fmt.Sprintf("index '%s' of dynamo source '%s'", idx.Name, ds.Name)
+	}
+	for i, k := range r.KeyFields {
+		keyPath := fmt.Sprintf("%s.keyFields[%d]", path, i)
+		if i >= len(keys) || keys[i] == nil {
+			v.addError(keyPath, "key field '%s' is not a key of %s", k.Name, of)
+			continue
+		}
+		if keys[i].Name != k.Name {
+			v.addError(keyPath, "key field '%s' does not match key '%s' of %s", k.Name, keys[i].Name, of)
+		}
+	}
+}
+
+func (v *validator) isKnownType(name string) bool {
+	if reScalarTypes.MatchString(name) {
+		return true
+	}
+	if _, ok := v.s.objectLookup[name]; ok {
+		return true
+	}
+	for _, e := range v.s.Enums {
+		if e.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isGeneratedInput reports whether the name is that of an input object
+// generated for the input of a query or mutation, which fields may take as
+// their input type to nest items, e.g. for transactions
+func (v *validator) isGeneratedInput(name string) bool {
+	resolvers := []*Resolver{}
+	for _, q := range v.s.Queries {
+		resolvers = append(resolvers, q.Resolver)
+	}
+	for _, m := range v.s.Mutations {
+		resolvers = append(resolvers, m.Resolver)
+	}
+	for _, r := range resolvers {
+		if r == nil || r.Type == nil {
+			continue
+		}
+		switch r.inputAction() {
+		case ActionInsert:
+			if name == fmt.Sprintf("Create%sInput", r.Type.Name) {
+				return true
+			}
+		case ActionUpdate:
+			if name == fmt.Sprintf("Update%sInput", r.Type.Name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parentError returns why the action can't be used by the kind of field
+// given, or an empty string where it can
+func parentError(action, parent string) string {
+	switch {
+	case parent == parentFunction:
+		// Functions are checked against the pipelines using them
+		return ""
+
+	// Search arguments and sort key conditions are only generated for queries
+	case (action == ActionSearch || action == ActionQuery) && parent != parentQuery:
+		return fmt.Sprintf("%s actions can only be used by queries", action)
+
+	// Events are published from the mutation's input, and batch writes and
+	// transactions take their items as arguments
+	case (action == ActionPublish || action == ActionBatchInsert || action == ActionBatchDelete || action == ActionTransact) && parent != parentMutation:
+		return fmt.Sprintf("%s actions can only be used by mutations", action)
+	}
+	return ""
+}
+
+func isSupportedAction(sourceType, action string) bool {
+	for _, a := range supportedActions[sourceType] {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// locate sets the line and column of each error by walking the manifest to
+// the node described by the error path. Where the exact node doesn't exist
+// (e.g. a missing key) the position of the closest parent is used.
+func (v *validator) locate(manifest []byte) {
+	var root yamlv3.Node
+	if err := yamlv3.Unmarshal(manifest, &root); err != nil || len(root.Content) == 0 {
+		return
+	}
+	for _, e := range v.errors {
+		n := root.Content[0]
+		for _, segment := range splitPath(e.Path) {
+			next := childNode(n, segment)
+			if next == nil {
+				break
+			}
+			n = next
+		}
+		e.Line, e.Column = n.Line, n.Column
+	}
+}
+
+// splitPath splits a path such as `objects[1].fields[0].type` into its
+// segments: objects, 1, fields, 0, type
+func splitPath(path string) []string {
+	path = strings.ReplaceAll(path, "[", ".")
+	path = strings.ReplaceAll(path, "]", "")
+	return strings.Split(path, ".")
+}
+
+func childNode(n *yamlv3.Node, segment string) *yamlv3.Node {
+	switch n.Kind {
+	case yamlv3.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == segment {
+				return n.Content[i+1]
+			}
+		}
+	case yamlv3.SequenceNode:
+		i, err := strconv.Atoi(segment)
+		if err == nil && i >= 0 && i < len(n.Content) {
+			return n.Content[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,821 @@
+package graphql_test
+
+import (
+	"testing"
+
+	"github.com/ONSdigital/aws-appsync-generator/pkg/graphql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	for _, c := range []struct {
+		scenario string
+		manifest []byte
+		expected []string
+	}{
+		{
+			"Valid manifest",
+			validManifest,
+			[]string{},
+		},
+		{
+			"Unknown types",
+			[]byte(`
+sources:
+  default:
+    name: animals
+    dynamo:
+      hash_key:
+        name: id
+objects:
+  - name: Animal
+    fields:
+      - name: id
+        type: ID!
+      - name: owner
+        type: Person
+queries:
+  - name: getToy
+    resolver:
+      action: get
+      type: Toy
+      keyFields:
+        - name: id
+`),
+			[]string{
+				"14:15: objects[0].fields[1].type: unknown type 'Person'",
+				"19:13: queries[0].resolver.type: unknown type 'Toy'",
+			},
+		},
+		{
+			"Duplicate names",
+			[]byte(`
+enums:
+  - name: Animal
+    values: [CAT]
+objects:
+  - name: Animal
+    fields:
+      - name: id
+queries:
+  - name: getAnimal
+    resolver:
+      action: get
+      type: Animal
+      source: default
+  - name: getAnimal
+    resolver:
+      action: get
+      type: Animal
+      source: default
+`),
+			[]string{
+				"6:11: objects[0].name: duplicate type name 'Animal' (also declared at enums[0].name)",
+				"15:11: queries[1].name: duplicate query name 'getAnimal' (also declared at queries[0].name)",
+				"14:15: queries[0].resolver.source: unknown source 'default'",
+				"19:15: queries[1].resolver.source: unknown source 'default'",
+			},
+		},
+		{
+			"Bad resolver keys and types",
+			[]byte(`
+sources:
+  default:
+    name: animals
+    dynamo:
+      hash_key:
+        name: id
+      sort_key:
+        name: name
+objects:
+  - name: Animal
+    fields:
+      - name: id
+      - name: name
+queries:
+  - name: getAnimal
+    resolver:
+      action: get
+      type: Animal
+      keyFields:
+        - name: name
+        - name: ident
+  - name: listAnimals
+    resolver:
+      action: list
+      type: Animal
+mutations:
+  - name: deleteAnimal
+    resolver:
+      action: delete
+      type: Animal
+`),
+			[]string{
+				"22:11: queries[0].resolver.keyFields[1]: key field 'ident' is not a field of 'Animal'",
+				"21:11: queries[0].resolver.keyFields[0]: key field 'name' does not match key 'id' of dynamo source 'animals'",
+				"22:11: queries[0].resolver.keyFields[1]: key field 'ident' does not match key 'name' of dynamo source 'animals'",
+				"26:13: queries[1].resolver.type: list actions must declare a list type, e.g. [Animal]",
+				"30:7: mutations[0].resolver: delete actions must declare keyFields",
+			},
+		},
+		{
+			"Bad subscriptions",
+			[]byte(`
+sources:
+  default:
+    name: animals
+    dynamo:
+      hash_key:
+        name: id
+objects:
+  - name: Animal
+    fields:
+      - name: id
+      - name: owner
+        type: Person
+  - name: Person
+    fields:
+      - name: id
+mutations:
+  - name: createAnimal
+    resolver:
+      action: insert
+      type: Animal
+  - name: createPerson
+    resolver:
+      action: insert
+      type: Person
+subscriptions:
+  - name: onCreate
+    mutations: [createAnimal, createPerson, createToy]
+    arguments:
+      - name: owner
+        type: Person
+      - name: species
+  - name: onCreate
+    mutations: []
+`),
+			[]string{
+				"33:11: subscriptions[1].name: duplicate subscription name 'onCreate' (also declared at subscriptions[0].name)",
+				"28:31: subscriptions[0].mutations[1]: mutation 'createPerson' returns 'Person' but the subscription returns 'Animal'",
+				"28:45: subscriptions[0].mutations[2]: unknown mutation 'createToy'",
+				"31:15: subscriptions[0].arguments[0].type: subscription arguments must be scalar or enum types, not 'Person'",
+				"32:9: subscriptions[0].arguments[1]: argument 'species' is not a field of 'Animal'",
+				"34:16: subscriptions[1].mutations: subscriptions must declare at least one mutation",
+			},
+		},
+		{
+			"Unconfigured auth",
+			[]byte(`
+sources:
+  default:
+    name: animals
+    dynamo:
+      hash_key:
+        name: id
+objects:
+  - name: Animal
+    auth: [AWS_IAM, API_KEY]
+    fields:
+      - name: id
+        auth: [OPENID_CONNECT]
+queries:
+  - name: getAnimal
+    auth: [AMAZON_COGNITO_USER_POOLS]
+    resolver:
+      action: get
+      type: Animal
+      keyFields:
+        - name: id
+`),
+			[]string{
+				"10:21: objects[0].auth[1]: auth type 'API_KEY' is not configured for the api",
+				"13:16: objects[0].fields[0].auth[0]: auth type 'OPENID_CONNECT' is not configured for the api",
+				"16:12: queries[0].auth[0]: auth type 'AMAZON_COGNITO_USER_POOLS' is not configured for the api",
+			},
+		},
+		{
+			"Bad functions and pipelines",
+			[]byte(`
+sources:
+  default:
+    name: animals
+    dynamo:
+      hash_key:
+        name: id
+objects:
+  - name: Animal
+    fields:
+      - name: id
+functions:
+  - name: fetch
+    source: toys
+    request: '{}'
+    response: '{}'
+  - name: fetch
+    action: list
+    type: Animal
+queries:
+  - name: getAnimal
+    resolver:
+      type: Animal
+      source: default
+      pipeline: [fetch, enrich]
+`),
+			[]string{
+				"14:13: functions[0].source: unknown source 'toys'",
+				"17:11: functions[1].name: duplicate function name 'fetch' (also declared at functions[0].name)",
+				"19:11: functions[1].type: list actions must declare a list type, e.g. [Animal]",
+				"24:15: queries[0].resolver.source: pipeline resolvers cannot declare a source, each function has its own",
+				"25:25: queries[0].resolver.pipeline[1]: unknown function 'enrich'",
+			},
+		},
+		{
+			"Functions used by the wrong kind of field",
+			[]byte(`
+sources:
+  default:
+    name: animals
+    dynamo:
+      hash_key:
+        name: id
+  events:
+    name: events
+    eventbridge:
+      bus_arn: arn:aws:events:eu-west-2:123456789012:event-bus/zoo
+      source: com.example.zoo
+objects:
+  - name: Animal
+    fields:
+      - name: id
+      - name: friends
+        resolver:
+          type: [Animal]
+          pipeline: [storeAll]
+functions:
+  - name: notify
+    source: events
+    action: publish
+    type: Animal
+  - name: storeAll
+    action: batch-insert
+    type: [Animal]
+  - name: byId
+    action: query
+    type: [Animal]
+    keyFields:
+      - name: id
+queries:
+  - name: notifyAnimal
+    resolver:
+      type: Animal
+      pipeline: [notify]
+  - name: findAnimals
+    resolver:
+      type: [Animal]
+      pipeline: [byId]
+mutations:
+  - name: createAnimal
+    resolver:
+      type: Animal
+      pipeline: [notify]
+  - name: storeAnimals
+    resolver:
+      type: [Animal]
+      pipeline: [storeAll, byId]
+`),
+			[]string{
+				"20:22: objects[0].fields[1].resolver.pipeline[0]: function 'storeAll': batch-insert actions can only be used by mutations",
+				"38:18: queries[0].resolver.pipeline[0]: function 'notify': publish actions can only be used by mutations",
+				"51:28: mutations[1].resolver.pipeline[1]: function 'byId': query actions can only be used by queries",
+			},
+		},
+		{
+			"Bad http requests",
+			[]byte(`
+sources:
+  default:
+    name: animals
+    http:
+      endpoint: https://api.example.com
+  table:
+    name: table
+    dynamo:
+      hash_key:
+        name: id
+objects:
+  - name: Animal
+    fields:
+      - name: id
+queries:
+  - name: getAnimal
+    resolver:
+      action: get
+      type: Animal
+  - name: findAnimal
+    resolver:
+      action: get
+      type: Animal
+      http:
+        path: /animals
+        query:
+          name: name
+        body: animal
+  - name: readAnimal
+    resolver:
+      action: get
+      type: Animal
+      source: table
+      keyFields:
+        - name: id
+      http:
+        path: /animals
+`),
+			[]string{
+				"19:7: queries[0].resolver: resolvers using http source 'animals' must declare an http request",
+				"28:17: queries[1].resolver.http.query.name: query parameter 'name' refers to unknown argument 'name'",
+				"29:15: queries[1].resolver.http.body: body refers to unknown argument 'animal'",
+				"38:9: queries[2].resolver.http: http requests can only be made to http sources, not dynamo source 'table'",
+			},
+		},
+		{
+			"Bad searches",
+			[]byte(`
+sources:
+  default:
+    name: animals
+    opensearch:
+      endpoint: https://search-zoo.eu-west-2.es.amazonaws.com
+      domain_arn: arn:aws:es:eu-west-2:123456789012:domain/zoo
+objects:
+  - name: Animal
+    fields:
+      - name: id
+      - name: friends
+        resolver:
+          action: search
+          type: [Animal]
+queries:
+  - name: searchAnimal
+    resolver:
+      action: search
+      type: Animal
+  - name: getAnimal
+    resolver:
+      action: get
+      type: Animal
+`),
+			[]string{
+				"14:19: objects[0].fields[1].resolver.action: search actions can only be used by queries",
+				"20:13: queries[0].resolver.type: search actions must declare a list type, e.g. [Animal]",
+				"23:15: queries[1].resolver.action: action 'get' is not supported by opensearch source 'animals'",
+			},
+		},
+		{
+			"Bad events",
+			[]byte(`
+sources:
+  default:
+    name: events
+    eventbridge:
+      bus_arn: arn:aws:events:eu-west-2:123456789012:event-bus/zoo
+      source: com.example.zoo
+objects:
+  - name: Animal
+    fields:
+      - name: id
+queries:
+  - name: animalFed
+    resolver:
+      action: publish
+      type: Animal
+mutations:
+  - name: animalNamed
+    resolver:
+      action: publish
+      type: String
+  - name: animalMoved
+    resolver:
+      action: local
+      type: Animal
+`),
+			[]string{
+				"15:15: queries[0].resolver.action: publish actions can only be used by mutations",
+				"21:13: mutations[0].resolver.type: publish actions must return an object type to take its input, not 'String'",
+				"24:15: mutations[1].resolver.action: action 'local' is not supported by eventbridge source 'events'",
+			},
+		},
+		{
+			"Bad indexes",
+			[]byte(`
+sources:
+  default:
+    name: animals
+    dynamo:
+      hash_key:
+        name: id
+      gsi:
+        - name: bySpecies
+          hash_key:
+            name: species
+objects:
+  - name: Animal
+    fields:
+      - name: id
+      - name: species
+queries:
+  - name: animalsByName
+    resolver:
+      action: get-items
+      type: Animal
+      index: byName
+  - name: animalsBySpecies
+    resolver:
+      action: get-items
+      type: Animal
+      index: bySpecies
+      keyFields:
+        - name: id
+  - name: getAnimal
+    resolver:
+      action: get
+      type: Animal
+      index: bySpecies
+      keyFields:
+        - name: species
+`),
+			[]string{
+				"22:14: queries[0].resolver.index: unknown index 'byName' of dynamo source 'animals'",
+				"29:11: queries[1].resolver.keyFields[0]: key field 'id' does not match key 'species' of index 'bySpecies' of dynamo source 'animals'",
+				"34:14: queries[2].resolver.index: get actions cannot use an index",
+			},
+		},
+		{
+			"Bad queries",
+			[]byte(`
+sources:
+  default:
+    name: animals
+    dynamo:
+      hash_key:
+        name: zooId
+      sort_key:
+        name: id
+objects:
+  - name: Animal
+    fields:
+      - name: zooId
+      - name: id
+      - name: offspring
+        resolver:
+          action: query
+          type: [Animal]
+          keyFields:
+            - name: zooId
+queries:
+  - name: queryAnimals
+    resolver:
+      action: query
+      type: [Animal]
+  - name: queryAnimal
+    resolver:
+      action: query
+      type: Animal
+      keyFields:
+        - name: id
+`),
+			[]string{
+				"17:19: objects[0].fields[2].resolver.action: query actions can only be used by queries",
+				"24:7: queries[0].resolver: query actions must declare keyFields",
+				"29:13: queries[1].resolver.type: query actions must declare a list type, e.g. [Animal]",
+				"31:11: queries[1].resolver.keyFields[0]: key field 'id' does not match key 'zooId' of dynamo source 'animals'",
+			},
+		},
+		{
+			"Bad batches",
+			[]byte(`
+sources:
+  default:
+    name: animals
+    dynamo:
+      hash_key:
+        name: zooId
+      sort_key:
+        name: id
+objects:
+  - name: Animal
+    fields:
+      - name: zooId
+      - name: id
+queries:
+  - name: getAnimals
+    resolver:
+      action: batch-get
+      type: [Animal]
+      keyFields:
+        - name: zooId
+  - name: createAnimals
+    resolver:
+      action: batch-insert
+      type: [Animal]
+mutations:
+  - name: deleteAnimals
+    resolver:
+      action: batch-delete
+      type: Animal
+`),
+			[]string{
+				"21:9: queries[0].resolver.keyFields: batch-get actions must declare both the hash and sort keys of dynamo source 'animals'",
+				"24:15: queries[1].resolver.action: batch-insert actions can only be used by mutations",
+				"30:13: mutations[0].resolver.type: batch-delete actions must declare a list type, e.g. [Animal]",
+				"29:7: mutations[0].resolver: batch-delete actions must declare keyFields",
+			},
+		},
+		{
+			"Bad transactions",
+			[]byte(`
+sources:
+  default:
+    name: zoos
+    dynamo:
+      hash_key:
+        name: zooId
+  search:
+    name: search
+    opensearch:
+      endpoint: https://search.example.com
+      domain_arn: arn:aws:es:eu-west-2:123456789012:domain/zoos
+objects:
+  - name: Zoo
+    fields:
+      - name: zooId
+queries:
+  - name: createZoo
+    resolver:
+      action: transact
+      type: Zoo
+      transact:
+        - operation: put
+mutations:
+  - name: updateZoo
+    resolver:
+      action: transact
+      type: Zoo
+  - name: deleteZoo
+    resolver:
+      action: transact
+      type: Zoo
+      transact:
+        - operation: delete
+          source: animals
+          item: zoo
+        - operation: put
+          source: search
+  - name: insertZoo
+    resolver:
+      action: insert
+      type: Zoo
+      transact:
+        - operation: put
+`),
+			[]string{
+				"20:15: queries[0].resolver.action: transact actions can only be used by mutations",
+				"27:7: mutations[0].resolver: transact actions must declare the steps of the transaction",
+				"36:17: mutations[1].resolver.transact[0].item: item refers to unknown argument 'zoo'",
+				"35:19: mutations[1].resolver.transact[0].source: unknown source 'animals'",
+				"38:19: mutations[1].resolver.transact[1].source: transact steps can only write to dynamo sources, not opensearch source 'search'",
+				"44:9: mutations[2].resolver.transact: transact steps can only be declared by transact actions",
+			},
+		},
+		{
+			"Bad ttls",
+			[]byte(`
+sources:
+  default:
+    name: sessions
+    dynamo:
+      hash_key:
+        name: id
+      ttl:
+        attribute: expiresAt
+  tokens:
+    name: tokens
+    dynamo:
+      hash_key:
+        name: id
+objects:
+  - name: Session
+    fields:
+      - name: id
+      - name: expiresAt
+        type: AWSDateTime
+        ttl: {}
+      - name: expiresAtEpoch
+        type: AWSTimestamp
+        ttl: {}
+mutations:
+  - name: createSession
+    resolver:
+      action: insert
+      type: Session
+  - name: createToken
+    resolver:
+      action: insert
+      type: Session
+      source: tokens
+`),
+			[]string{
+				"24:14: objects[0].fields[2].ttl: object 'Session' already declares field 'expiresAt' as its ttl",
+				"28:7: mutations[0].resolver: ttl field 'expiresAt' of type AWSDateTime cannot be stored in ttl attribute 'expiresAt' of dynamo source 'sessions', which holds epoch seconds",
+				"32:7: mutations[1].resolver: dynamo source 'tokens' must declare a ttl to store the expiry of 'Session'",
+			},
+		},
+		{
+			"Bad sql inserts",
+			[]byte(`
+sources:
+  default:
+    name: people
+    sql:
+      cluster_arn: cluster
+      secret_arn: secret
+      database: zoo
+objects:
+  - name: Person
+    fields:
+      - name: id
+        type: ID!
+mutations:
+  - name: createPerson
+    resolver:
+      action: insert
+      type: Person
+`),
+			[]string{
+				"17:7: mutations[0].resolver: insert actions using sql source 'people' must declare the keyFields given in the input to read back the inserted row",
+			},
+		},
+		{
+			"Versioned objects written without their version",
+			[]byte(`
+sources:
+  default:
+    name: zoos
+    dynamo:
+      hash_key:
+        name: id
+  people:
+    name: people
+    sql:
+      cluster_arn: cluster
+      secret_arn: secret
+      database: zoo
+objects:
+  - name: Animal
+    versioned: true
+    fields:
+      - name: id
+        type: ID!
+  - name: Zoo
+    versioned: true
+    fields:
+      - name: id
+        type: ID!
+      - name: animals
+        type: [Animal]
+        inputType: CreateAnimalInput
+mutations:
+  - name: createAnimal
+    resolver:
+      action: insert
+      type: Animal
+  - name: renameZoo
+    resolver:
+      action: transact
+      type: Zoo
+      transact:
+        - operation: update
+          exclude: [animals]
+        - operation: delete
+          item: input.animals
+  - name: updateAnimal
+    resolver:
+      action: update
+      type: Animal
+      source: people
+      keyFields:
+        - name: id
+`),
+			[]string{
+				"38:22: mutations[1].resolver.transact[0].operation: transact update steps cannot write versioned object 'Zoo'",
+				"40:22: mutations[1].resolver.transact[1].operation: transact delete steps cannot write versioned object 'Animal'",
+				"45:13: mutations[2].resolver.type: versioned object 'Animal' can only be stored by dynamo sources, not sql source 'people'",
+			},
+		},
+		{
+			"Objects found by generated ids in transactions",
+			[]byte(`
+sources:
+  default:
+    name: zoos
+    dynamo:
+      hash_key:
+        name: id
+objects:
+  - name: Zoo
+    fields:
+      - name: id
+        type: ID!
+        generated: uuid
+      - name: name
+      - name: updatedAt
+        type: AWSDateTime
+        generated: updatedAt
+mutations:
+  - name: createZoo
+    resolver:
+      action: insert
+      type: Zoo
+  - name: renameZoo
+    resolver:
+      action: transact
+      type: Zoo
+      transact:
+        - operation: put
+        - operation: update
+`),
+			[]string{
+				"29:22: mutations[1].resolver.transact[1].operation: transact update steps cannot find object 'Zoo' by its generated field 'id'",
+			},
+		},
+	} {
+		s, err := graphql.NewSchemaFromManifest(c.manifest)
+		if err != nil {
+			t.Fatalf("%s: unable to parse manifest: %v", c.scenario, err)
+		}
+		errs := []string{}
+		for _, e := range s.Validate() {
+			errs = append(errs, e.Error())
+		}
+		assert.Equal(t, c.expected, errs, c.scenario)
+	}
+}
+
+var validManifest = []byte(`
+sources:
+  default:
+    name: animals
+    dynamo:
+      hash_key:
+        name: id
+
+enums:
+  - name: Species
+    values: [CAT,DOG]
+
+objects:
+  - name: Animal
+    fields:
+      - name: id
+        type: ID!
+      - name: species
+        type: Species
+      - name: born
+        type: AWSDate
+
+queries:
+  - name: getAnimal
+    resolver:
+      action: get
+      type: Animal
+      keyFields:
+        - name: id
+          type: ID
+  - name: listAnimals
+    resolver:
+      action: list
+      type: [Animal]
+
+mutations:
+  - name: createAnimal
+    resolver:
+      action: insert
+      type: Animal
+      keyFields:
+        - name: id
+          type: ID
+  - name: deleteAnimal
+    resolver:
+      action: delete
+      type: Animal
+      keyFields:
+        - name: id
+          type: ID
+
+subscriptions:
+  - name: onCreateAnimal
+    mutations: [createAnimal]
+    arguments:
+      - name: species
+        type: Species
+`)
@@ -0,0 +1,12 @@
+// Package templates holds the built-in resolver mapping templates. They are
+// embedded in the generator binary so that it can be run from any directory.
+package templates
+
+import "embed"
+
+// Resolvers contains the built-in resolver templates, laid out as
+// resolvers/<source>/<request|response>/<action>.tmpl, except those shared by
+// every http action at resolvers/http/<request|response>.tmpl
+//
+//go:embed resolvers
+var Resolvers embed.FS